@@ -0,0 +1,750 @@
+package jsonld
+
+import (
+	"sort"
+	"strings"
+)
+
+// Compact compacts a JSON-LD document with the context ctx, as defined in
+// https://www.w3.org/TR/json-ld11-api/#compaction-algorithm.
+//
+// The input document must be a value decoded by the encoding/json package. It
+// is expanded before being compacted. If ctx is non-nil, the result contains
+// it in its @context entry.
+func Compact(input interface{}, ctx *Context, opts *Options) (map[string]interface{}, error) {
+	p := newProcessor(opts)
+	expanded, err := p.expandDocument(input)
+	if err != nil {
+		return nil, err
+	}
+	return p.compactDocument(expanded, ctx)
+}
+
+func (p *processor) compactDocument(expanded []interface{}, ctx *Context) (map[string]interface{}, error) {
+	active := (&Context{Base: p.opts.Base}).newChild(ctx)
+
+	v, err := p.compact(active, "", expanded)
+	if err != nil {
+		return nil, err
+	}
+
+	result, ok := v.(map[string]interface{})
+	if !ok {
+		result = make(map[string]interface{})
+		if values := toArray(v); len(values) > 0 {
+			result[p.compactIRI(active, "@graph", nil, true, false)] = values
+		}
+	}
+
+	if ctx != nil {
+		formatted := formatContext(ctx)
+		if m, ok := formatted.(map[string]interface{}); !ok || len(m) > 0 {
+			result["@context"] = formatted
+		}
+	}
+
+	return result, nil
+}
+
+// inverseContext maps IRIs to containers to type/language selectors to
+// values to terms, as defined in
+// https://www.w3.org/TR/json-ld11-api/#inverse-context-creation.
+type inverseContext map[string]map[string]map[string]map[string]string
+
+// sortedTerms returns the terms of ctx, shortest first and then in
+// lexicographical order.
+func sortedTerms(ctx *Context) []string {
+	terms := make([]string, 0, len(ctx.Terms))
+	for k := range ctx.Terms {
+		terms = append(terms, k)
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		if len(terms[i]) != len(terms[j]) {
+			return len(terms[i]) < len(terms[j])
+		}
+		return terms[i] < terms[j]
+	})
+	return terms
+}
+
+func setIfMissing(m map[string]string, k, v string) {
+	if _, ok := m[k]; !ok {
+		m[k] = v
+	}
+}
+
+func (p *processor) inverseContext(ctx *Context) inverseContext {
+	if inverse, ok := p.inverses[ctx]; ok {
+		return inverse
+	}
+
+	defaultLang := ctx.Lang
+	if defaultLang == "" {
+		defaultLang = "@none"
+	}
+
+	inverse := make(inverseContext)
+	for _, k := range sortedTerms(ctx) {
+		term := ctx.Terms[k]
+		if term == nil || term.ID == "" {
+			continue
+		}
+
+		var containers []string
+		for _, c := range term.Props["@container"] {
+			containers = append(containers, c.(string))
+		}
+		sort.Strings(containers)
+		container := strings.Join(containers, "")
+		if container == "" {
+			container = "@none"
+		}
+
+		containerMap, ok := inverse[term.ID]
+		if !ok {
+			containerMap = make(map[string]map[string]map[string]string)
+			inverse[term.ID] = containerMap
+		}
+		typeLangMap, ok := containerMap[container]
+		if !ok {
+			typeLangMap = map[string]map[string]string{
+				"@language": make(map[string]string),
+				"@type": make(map[string]string),
+				"@any": {"@none": k},
+			}
+			containerMap[container] = typeLangMap
+		}
+		langMap := typeLangMap["@language"]
+		typeMap := typeLangMap["@type"]
+
+		lang, hasLang := termLanguage(term)
+		dir, hasDir := termDirection(term)
+		if termIsReverse(term) {
+			setIfMissing(typeMap, "@reverse", k)
+		} else if t := termType(term); t == "@none" {
+			setIfMissing(langMap, "@any", k)
+			setIfMissing(typeMap, "@any", k)
+		} else if t != "" {
+			setIfMissing(typeMap, t, k)
+		} else if hasLang || hasDir {
+			langDir := langDirKey(lang, dir)
+			if langDir == "" {
+				langDir = "@null"
+			}
+			setIfMissing(langMap, langDir, k)
+		} else if ctx.Direction != "" {
+			setIfMissing(langMap, langDirKey(ctx.Lang, ctx.Direction), k)
+			setIfMissing(langMap, "@none", k)
+			setIfMissing(typeMap, "@none", k)
+		} else {
+			setIfMissing(langMap, defaultLang, k)
+			setIfMissing(langMap, "@none", k)
+			setIfMissing(typeMap, "@none", k)
+		}
+	}
+
+	if p.inverses == nil {
+		p.inverses = make(map[*Context]inverseContext)
+	}
+	p.inverses[ctx] = inverse
+	return inverse
+}
+
+// langDirKey returns the inverse context key for a language and a base
+// direction, such as "en_rtl" or "_rtl".
+func langDirKey(lang, dir string) string {
+	if dir == "" {
+		return lang
+	}
+	return lang + "_" + dir
+}
+
+// selectTerm selects the best term for an IRI, as defined in
+// https://www.w3.org/TR/json-ld11-api/#term-selection.
+func (inverse inverseContext) selectTerm(iri string, containers []string, typeLang string, preferred []string) string {
+	containerMap := inverse[iri]
+	for _, c := range containers {
+		typeLangMap, ok := containerMap[c]
+		if !ok {
+			continue
+		}
+		valueMap := typeLangMap[typeLang]
+		for _, v := range preferred {
+			if term, ok := valueMap[v]; ok {
+				return term
+			}
+		}
+	}
+	return ""
+}
+
+func isValueObject(v interface{}) bool {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	_, ok = m["@value"]
+	return ok
+}
+
+// compactIRI compacts an IRI, as defined in
+// https://www.w3.org/TR/json-ld11-api/#iri-compaction. value is the value
+// associated with the IRI, if any.
+func (p *processor) compactIRI(ctx *Context, iri string, value interface{}, vocab, reverse bool) string {
+	if iri == "" {
+		return iri
+	}
+
+	inverse := p.inverseContext(ctx)
+	if _, ok := inverse[iri]; vocab && ok {
+		if term := p.selectIRITerm(ctx, inverse, iri, value, reverse); term != "" {
+			return term
+		}
+	}
+
+	if isKeyword(iri) {
+		return iri
+	}
+
+	if vocab && ctx.Vocab != "" && strings.HasPrefix(iri, ctx.Vocab) && len(iri) > len(ctx.Vocab) {
+		suffix := iri[len(ctx.Vocab):]
+		if _, ok := ctx.Terms[suffix]; !ok && !confusedWithPrefix(ctx, suffix) {
+			return suffix
+		}
+	}
+
+	compactIRI := ""
+	for _, k := range sortedTerms(ctx) {
+		term := ctx.Terms[k]
+		if term == nil || strings.ContainsAny(k, ":/") || term.ID == iri || !strings.HasPrefix(iri, term.ID) || !termIsPrefix(term) {
+			continue
+		}
+
+		candidate := k + ":" + iri[len(term.ID):]
+		candidateTerm, isTerm := ctx.Terms[candidate]
+		shorter := compactIRI == "" || len(candidate) < len(compactIRI) || (len(candidate) == len(compactIRI) && candidate < compactIRI)
+		if (shorter && !isTerm) || (isTerm && candidateTerm != nil && candidateTerm.ID == iri && value == nil) {
+			compactIRI = candidate
+		}
+	}
+	if compactIRI != "" {
+		return compactIRI
+	}
+
+	if !vocab && !p.opts.KeepAbsoluteIRIs {
+		iri = RelativeIRI(ctx.Base, iri)
+		if looksLikeKeyword(iri) {
+			// Relative IRIs must not be mistaken for keywords
+			return "./" + iri
+		}
+	}
+
+	if confusedWithPrefix(ctx, iri) && p.compactErr == nil {
+		p.compactErr = errorf("IRI confused with prefix", "%q", iri)
+	}
+
+	return iri
+}
+
+// confusedWithPrefix checks whether an IRI would be mistaken for a compact IRI
+// when expanded, ie. whether its scheme is a prefix term.
+func confusedWithPrefix(ctx *Context, iri string) bool {
+	i := strings.IndexByte(iri, ':')
+	if i <= 0 || strings.HasPrefix(iri[i+1:], "//") {
+		return false
+	}
+	return termIsPrefix(ctx.Terms[iri[:i]])
+}
+
+func (p *processor) selectIRITerm(ctx *Context, inverse inverseContext, iri string, value interface{}, reverse bool) string {
+	defaultLang := ctx.Lang
+	if defaultLang == "" {
+		defaultLang = "@none"
+	}
+
+	m, _ := value.(map[string]interface{})
+
+	var containers []string
+	typeLang := "@language"
+	typeLangValue := "@null"
+
+	if _, ok := m["@index"]; ok {
+		containers = append(containers, "@index", "@index@set")
+	}
+
+	if reverse {
+		typeLang = "@type"
+		typeLangValue = "@reverse"
+		containers = append(containers, "@set")
+	} else if list, ok := m["@list"].([]interface{}); ok {
+		if _, ok := m["@index"]; !ok {
+			containers = append(containers, "@list")
+		}
+
+		var commonType, commonLang string
+		if len(list) == 0 {
+			commonLang = defaultLang
+		}
+		for _, item := range list {
+			itemLang, itemType := "@none", "@none"
+			if isValueObject(item) {
+				item := item.(map[string]interface{})
+				lang, hasLang := item["@language"].(string)
+				dir, hasDir := item["@direction"].(string)
+				if hasLang || hasDir {
+					itemLang = langDirKey(lang, dir)
+				} else if t, ok := item["@type"].(string); ok {
+					itemType = t
+				} else {
+					itemLang = "@null"
+				}
+			} else {
+				itemType = "@id"
+			}
+
+			if commonLang == "" {
+				commonLang = itemLang
+			} else if commonLang != itemLang && isValueObject(item) {
+				commonLang = "@none"
+			}
+			if commonType == "" {
+				commonType = itemType
+			} else if commonType != itemType {
+				commonType = "@none"
+			}
+			if commonLang == "@none" && commonType == "@none" {
+				break
+			}
+		}
+		if commonLang == "" {
+			commonLang = "@none"
+		}
+		if commonType == "" {
+			commonType = "@none"
+		}
+
+		if commonType != "@none" {
+			typeLang = "@type"
+			typeLangValue = commonType
+		} else {
+			typeLangValue = commonLang
+		}
+	} else {
+		if isValueObject(m) {
+			_, hasIndex := m["@index"]
+			lang, hasLang := m["@language"].(string)
+			dir, hasDir := m["@direction"].(string)
+			if (hasLang || hasDir) && !hasIndex {
+				typeLangValue = langDirKey(lang, dir)
+				containers = append(containers, "@language", "@language@set")
+			} else if t, ok := m["@type"].(string); ok {
+				typeLang = "@type"
+				typeLangValue = t
+			}
+		} else {
+			typeLang = "@type"
+			typeLangValue = "@id"
+			containers = append(containers, "@id", "@id@set", "@type", "@set@type")
+		}
+		containers = append(containers, "@set")
+	}
+
+	containers = append(containers, "@none")
+	if !p.isJSONLD10() {
+		if _, ok := m["@index"]; !ok {
+			containers = append(containers, "@index", "@index@set")
+		}
+		if _, ok := m["@value"]; ok && len(m) == 1 {
+			containers = append(containers, "@language", "@language@set")
+		}
+	}
+
+	var preferred []string
+	if id, ok := m["@id"].(string); ok && typeLangValue == "@id" {
+		compacted := p.compactIRI(ctx, id, nil, true, false)
+		if term := ctx.Terms[compacted]; term != nil && term.ID == id {
+			preferred = append(preferred, "@vocab", "@id", "@none")
+		} else {
+			preferred = append(preferred, "@id", "@vocab", "@none")
+		}
+	} else {
+		if list, ok := m["@list"].([]interface{}); ok && len(list) == 0 {
+			typeLang = "@any"
+		}
+		preferred = append(preferred, typeLangValue, "@none")
+	}
+	preferred = append(preferred, "@any")
+
+	// Terms with only a direction mapping match values with any language
+	for _, v := range preferred {
+		if i := strings.IndexByte(v, '_'); i > 0 {
+			preferred = append(preferred, v[i:])
+			break
+		}
+	}
+
+	return inverse.selectTerm(iri, containers, typeLang, preferred)
+}
+
+// compactValue compacts a value object or a node reference, as defined in
+// https://www.w3.org/TR/json-ld11-api/#value-compaction.
+func (p *processor) compactValue(ctx *Context, prop string, value map[string]interface{}) interface{} {
+	term := ctx.Terms[prop]
+	t := termType(term)
+	lang := ctx.Lang
+	if l, ok := termLanguage(term); ok {
+		lang = l
+	}
+	dir := ctx.Direction
+	if d, ok := termDirection(term); ok {
+		dir = d
+	}
+
+	_, hasIndex := value["@index"]
+	keepIndex := hasIndex && !termHasContainer(term, "@index")
+
+	if id, ok := value["@id"].(string); ok {
+		if len(value) > 2 || (len(value) == 2 && !hasIndex) {
+			return value
+		}
+		if keepIndex {
+			return p.compactKeywords(ctx, value)
+		}
+		switch t {
+		case "@id":
+			return p.compactIRI(ctx, id, nil, false, false)
+		case "@vocab":
+			return p.compactIRI(ctx, id, nil, true, false)
+		}
+		return p.compactKeywords(ctx, value)
+	}
+
+	v := value["@value"]
+	valueType, hasType := value["@type"].(string)
+	valueLang, hasLang := value["@language"].(string)
+	valueDir, _ := value["@direction"].(string)
+
+	if hasType && valueType == t && !keepIndex {
+		return v
+	}
+	if t == "@none" || (hasType && valueType != t) {
+		result := make(map[string]interface{}, len(value))
+		for k, v := range value {
+			result[k] = v
+		}
+		if hasType {
+			result["@type"] = p.compactIRI(ctx, valueType, nil, true, false)
+		}
+		return p.compactKeywords(ctx, result)
+	}
+	if _, ok := v.(string); !ok && !keepIndex {
+		return v
+	}
+	if (strings.EqualFold(valueLang, lang) || (!hasLang && lang == "")) && valueDir == dir && !keepIndex {
+		return v
+	}
+
+	return p.compactKeywords(ctx, value)
+}
+
+// compactKeywords replaces keywords in the entries of m with their aliases.
+func (p *processor) compactKeywords(ctx *Context, m map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		result[p.compactIRI(ctx, k, nil, true, false)] = v
+	}
+	return result
+}
+
+// compact compacts an expanded element. prop is the active property, or an
+// empty string if there is none.
+func (p *processor) compact(ctx *Context, prop string, element interface{}) (interface{}, error) {
+	switch element := element.(type) {
+	case []interface{}:
+		result := make([]interface{}, 0, len(element))
+		for _, item := range element {
+			v, err := p.compact(ctx, prop, item)
+			if err != nil {
+				return nil, err
+			}
+			if v != nil {
+				result = append(result, v)
+			}
+		}
+
+		term := ctx.Terms[prop]
+		if len(result) != 1 || p.opts.KeepArrays || prop == "@graph" || prop == "@set" || termHasContainer(term, "@list") || termHasContainer(term, "@set") {
+			return result, nil
+		}
+		return result[0], nil
+	case map[string]interface{}:
+		v, err := p.compactMap(ctx, prop, element)
+		if err == nil && p.compactErr != nil {
+			err = p.compactErr
+		}
+		return v, err
+	default:
+		return element, nil
+	}
+}
+
+func (p *processor) compactMap(ctx *Context, prop string, m map[string]interface{}) (interface{}, error) {
+	_, hasValue := m["@value"]
+	_, hasID := m["@id"]
+
+	// Contexts which aren't propagated don't apply to nested node objects
+	term := ctx.Terms[prop]
+	if ctx.previous != nil && !hasValue && !(hasID && len(m) == 1) {
+		ctx = ctx.previous
+	}
+	ctx, err := p.applyScopedContext(ctx, term, propertyScope)
+	if err != nil {
+		return nil, err
+	}
+	if hasValue && m["@type"] == "@json" && termType(ctx.Terms[prop]) == "@json" {
+		if _, hasIndex := m["@index"]; !hasIndex || termHasContainer(ctx.Terms[prop], "@index") {
+			// JSON literals are kept verbatim
+			return m["@value"], nil
+		}
+	}
+	if hasValue || hasID {
+		v := p.compactValue(ctx, prop, m)
+		if _, ok := v.(map[string]interface{}); !ok {
+			return v, nil
+		}
+	}
+
+	// Types are compacted with the context in effect before type-scoped
+	// contexts are applied
+	typeCtx := ctx
+	if !hasValue {
+		var types []string
+		for _, t := range toArray(m["@type"]) {
+			s, _ := t.(string)
+			types = append(types, p.compactIRI(typeCtx, s, nil, true, false))
+		}
+		sort.Strings(types)
+		for _, t := range types {
+			if ctx, err = p.applyScopedContext(ctx, typeCtx.Terms[t], typeScope); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	result := make(map[string]interface{})
+	for _, k := range sortedKeys(m) {
+		v := m[k]
+
+		switch k {
+		case "@id":
+			id, _ := v.(string)
+			result[p.compactIRI(ctx, "@id", nil, true, false)] = p.compactIRI(ctx, id, nil, false, false)
+			continue
+		case "@type":
+			var types []interface{}
+			for _, t := range toArray(v) {
+				s, _ := t.(string)
+				types = append(types, p.compactIRI(typeCtx, s, nil, true, false))
+			}
+			alias := p.compactIRI(ctx, "@type", nil, true, false)
+			asArray := termHasContainer(ctx.Terms[alias], "@set") || p.opts.KeepArrays
+			if _, ok := v.(string); ok && !asArray {
+				result[alias] = types[0]
+			} else {
+				addValue(result, alias, types, asArray)
+			}
+			continue
+		case "@index":
+			// Indexes are used as keys in index maps
+			if termHasContainer(ctx.Terms[prop], "@index") {
+				continue
+			}
+			result[p.compactIRI(ctx, k, nil, true, false)] = v
+			continue
+		case "@language", "@direction", "@value":
+			result[p.compactIRI(ctx, k, nil, true, false)] = v
+			continue
+		case "@reverse":
+			reverse, _ := v.(map[string]interface{})
+			compacted, err := p.compactMap(ctx, "@reverse", reverse)
+			if err != nil {
+				return nil, err
+			}
+			compactedMap, _ := compacted.(map[string]interface{})
+			for k, v := range compactedMap {
+				// Reverse terms are moved out of the @reverse entry
+				if term := ctx.Terms[k]; termIsReverse(term) {
+					asArray := termHasContainer(term, "@set") || p.opts.KeepArrays
+					addValue(result, k, v, asArray)
+					delete(compactedMap, k)
+				}
+			}
+			if len(compactedMap) > 0 {
+				result[p.compactIRI(ctx, "@reverse", nil, true, false)] = compactedMap
+			}
+			continue
+		case "@preserve":
+			compacted, err := p.compact(ctx, prop, v)
+			if err != nil {
+				return nil, err
+			}
+			if a, ok := compacted.([]interface{}); !ok || len(a) > 0 {
+				result[k] = compacted
+			}
+			continue
+		}
+
+		values, ok := v.([]interface{})
+		if !ok {
+			if k == "@list" || k == "@graph" {
+				values = toArray(v)
+			} else {
+				continue
+			}
+		}
+
+		insideReverse := prop == "@reverse"
+		if len(values) == 0 {
+			itemProp := p.compactIRI(ctx, k, values, true, insideReverse)
+			nestResult, err := nestedResult(ctx, result, itemProp)
+			if err != nil {
+				return nil, err
+			}
+			addValue(nestResult, itemProp, values, true)
+		}
+
+		for _, item := range values {
+			itemProp := p.compactIRI(ctx, k, item, true, insideReverse)
+			term := ctx.Terms[itemProp]
+			asArray := termHasContainer(term, "@set") || itemProp == "@graph" || itemProp == "@list" || p.opts.KeepArrays
+
+			nestResult, err := nestedResult(ctx, result, itemProp)
+			if err != nil {
+				return nil, err
+			}
+
+			itemMap, _ := item.(map[string]interface{})
+			list, isList := itemMap["@list"]
+			if container := termMapContainer(term); container != "" && !isList {
+				if err := p.compactMapItem(ctx, nestResult, itemProp, container, itemMap, asArray); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if !isList {
+				compacted, err := p.compact(ctx, itemProp, item)
+				if err != nil {
+					return nil, err
+				}
+				addValue(nestResult, itemProp, compacted, asArray)
+				continue
+			}
+
+			compacted, err := p.compact(ctx, itemProp, list)
+			if err != nil {
+				return nil, err
+			}
+			compacted = toArray(compacted)
+			if !termHasContainer(term, "@list") {
+				listObject := map[string]interface{}{
+					p.compactIRI(ctx, "@list", nil, true, false): compacted,
+				}
+				if index, ok := itemMap["@index"]; ok {
+					listObject[p.compactIRI(ctx, "@index", nil, true, false)] = index
+				}
+				addValue(nestResult, itemProp, listObject, asArray)
+			} else {
+				nestResult[itemProp] = compacted
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// nestedResult returns the object the values of the term prop are added to:
+// the nested object of result designated by the @nest value of the term, or
+// result itself if the term isn't nested.
+func nestedResult(ctx *Context, result map[string]interface{}, prop string) (map[string]interface{}, error) {
+	nest := termNest(ctx.Terms[prop])
+	if nest == "" {
+		return result, nil
+	}
+	if nest != "@nest" {
+		if term := ctx.Terms[nest]; term == nil || term.ID != "@nest" {
+			return nil, errorf("invalid @nest value", "%q", nest)
+		}
+	}
+
+	nested, ok := result[nest].(map[string]interface{})
+	if !ok {
+		nested = make(map[string]interface{})
+		result[nest] = nested
+	}
+	return nested, nil
+}
+
+// compactMapItem compacts an item of a property with a map container, and adds
+// it to the language map, index map, ID map or type map of result, as defined
+// in step 12.8.9 of
+// https://www.w3.org/TR/json-ld11-api/#compaction-algorithm.
+func (p *processor) compactMapItem(ctx *Context, result map[string]interface{}, prop, container string, item map[string]interface{}, asArray bool) error {
+	mapObject, ok := result[prop].(map[string]interface{})
+	if !ok {
+		mapObject = make(map[string]interface{})
+		result[prop] = mapObject
+	}
+
+	compacted, err := p.compact(ctx, prop, item)
+	if err != nil {
+		return err
+	}
+	compactedMap, _ := compacted.(map[string]interface{})
+
+	var key string
+	switch container {
+	case "@language":
+		if v, ok := item["@value"]; ok {
+			compacted = v
+		}
+		key, _ = item["@language"].(string)
+	case "@index":
+		key, _ = item["@index"].(string)
+	case "@id":
+		if id, ok := item["@id"].(string); ok {
+			key = p.compactIRI(ctx, id, nil, false, false)
+		}
+		if compactedMap != nil {
+			delete(compactedMap, p.compactIRI(ctx, "@id", nil, true, false))
+		} else {
+			compacted = map[string]interface{}{}
+		}
+	case "@type":
+		if compactedMap == nil {
+			break
+		}
+		alias := p.compactIRI(ctx, "@type", nil, true, false)
+		types := toArray(compactedMap[alias])
+		delete(compactedMap, alias)
+		if len(types) > 0 {
+			key, _ = types[0].(string)
+			if len(types) > 1 {
+				addValue(compactedMap, alias, types[1:], false)
+			}
+		}
+		if len(compactedMap) == 1 {
+			if _, ok := compactedMap[p.compactIRI(ctx, "@id", nil, true, false)]; ok {
+				ref := map[string]interface{}{"@id": item["@id"]}
+				if compacted, err = p.compact(ctx, prop, ref); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if key == "" {
+		key = p.compactIRI(ctx, "@none", nil, true, false)
+	}
+	addValue(mapObject, key, compacted, asArray)
+	return nil
+}
@@ -0,0 +1,417 @@
+package jsonld
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+var compactTests = []struct{
+	name string
+	in string
+	ctx string
+	out string
+}{
+	{
+		name: "example5",
+		in: `[{
+			"http://schema.org/name": [{"@value": "Manu Sporny"}],
+			"http://schema.org/url": [{"@id": "http://manu.sporny.org/"}],
+			"http://schema.org/image": [{"@id": "http://manu.sporny.org/images/manu.png"}]
+		}]`,
+		ctx: `{
+			"name": "http://schema.org/name",
+			"image": {"@id": "http://schema.org/image", "@type": "@id"},
+			"homepage": {"@id": "http://schema.org/url", "@type": "@id"}
+		}`,
+		out: example5,
+	},
+	{
+		name: "term selection",
+		in: `{
+			"http://example.org/p": [
+				"plain",
+				{"@value": "english", "@language": "en"},
+				{"@value": "2020-01-01", "@type": "http://www.w3.org/2001/XMLSchema#date"},
+				{"@id": "http://example.org/node"}
+			]
+		}`,
+		ctx: `{
+			"ex": "http://example.org/",
+			"z": "http://example.org/p",
+			"a": "http://example.org/p",
+			"en": {"@id": "http://example.org/p", "@language": "en"},
+			"date": {"@id": "http://example.org/p", "@type": "http://www.w3.org/2001/XMLSchema#date"},
+			"ref": {"@id": "http://example.org/p", "@type": "@id"}
+		}`,
+		out: `{
+			"@context": {
+				"ex": "http://example.org/",
+				"z": "http://example.org/p",
+				"a": "http://example.org/p",
+				"en": {"@id": "http://example.org/p", "@language": "en"},
+				"date": {"@id": "http://example.org/p", "@type": "http://www.w3.org/2001/XMLSchema#date"},
+				"ref": {"@id": "http://example.org/p", "@type": "@id"}
+			},
+			"a": "plain",
+			"en": "english",
+			"date": "2020-01-01",
+			"ref": "ex:node"
+		}`,
+	},
+	{
+		name: "compact IRI and vocab",
+		in: `{
+			"@id": "http://example.org/vocab#me",
+			"@type": "http://xmlns.com/foaf/0.1/Person",
+			"http://example.org/vocab#name": "Me",
+			"http://xmlns.com/foaf/0.1/knows": {"@id": "_:b0"}
+		}`,
+		ctx: `{
+			"@vocab": "http://example.org/vocab#",
+			"foaf": "http://xmlns.com/foaf/0.1/",
+			"foaf2": "http://xmlns.com/foaf/"
+		}`,
+		out: `{
+			"@context": {
+				"@vocab": "http://example.org/vocab#",
+				"foaf": "http://xmlns.com/foaf/0.1/",
+				"foaf2": "http://xmlns.com/foaf/"
+			},
+			"@id": "http://example.org/vocab#me",
+			"@type": "foaf:Person",
+			"name": "Me",
+			"foaf:knows": {"@id": "_:b0"}
+		}`,
+	},
+	{
+		name: "list",
+		in: `{
+			"http://example.org/list": {"@list": ["a", "b"]},
+			"http://example.org/other": {"@list": ["c"]}
+		}`,
+		ctx: `{
+			"ex": "http://example.org/",
+			"list": {"@id": "http://example.org/list", "@container": "@list"},
+			"set": {"@id": "http://example.org/set", "@container": "@set"}
+		}`,
+		out: `{
+			"@context": {
+				"ex": "http://example.org/",
+				"list": {"@id": "http://example.org/list", "@container": "@list"},
+				"set": {"@id": "http://example.org/set", "@container": "@set"}
+			},
+			"list": ["a", "b"],
+			"ex:other": {"@list": ["c"]}
+		}`,
+	},
+	{
+		name: "graph",
+		in: `[
+			{"@id": "http://example.org/a", "http://example.org/p": "a"},
+			{"@id": "http://example.org/b", "http://example.org/p": "b"}
+		]`,
+		ctx: `{"p": "http://example.org/p"}`,
+		out: `{
+			"@context": {"p": "http://example.org/p"},
+			"@graph": [
+				{"@id": "http://example.org/a", "p": "a"},
+				{"@id": "http://example.org/b", "p": "b"}
+			]
+		}`,
+	},
+	{
+		name: "direction",
+		in: `[{
+			"http://example.org/title": [{"@value": "كتاب", "@language": "ar", "@direction": "rtl"}],
+			"http://example.org/english": [{"@value": "Book", "@language": "en", "@direction": "ltr"}],
+			"http://example.org/code": [{"@value": "B-1"}],
+			"http://example.org/other": [{"@value": "ספר", "@language": "he", "@direction": "rtl"}]
+		}]`,
+		ctx: `{
+			"@vocab": "http://example.org/",
+			"@language": "ar",
+			"@direction": "rtl",
+			"english": {"@id": "http://example.org/english", "@language": "en", "@direction": "ltr"},
+			"code": {"@id": "http://example.org/code", "@language": null, "@direction": null}
+		}`,
+		out: `{
+			"@context": {
+				"@vocab": "http://example.org/",
+				"@language": "ar",
+				"@direction": "rtl",
+				"english": {"@id": "http://example.org/english", "@language": "en", "@direction": "ltr"},
+				"code": {"@id": "http://example.org/code", "@language": null, "@direction": null}
+			},
+			"title": "كتاب",
+			"english": "Book",
+			"code": "B-1",
+			"other": {"@value": "ספר", "@language": "he", "@direction": "rtl"}
+		}`,
+	},
+	{
+		name: "container maps",
+		in: exampleContainerMaps,
+		ctx: `{
+			"@vocab": "http://example.org/",
+			"title": {"@id": "http://example.org/title", "@container": "@language"},
+			"posts": {"@id": "http://example.org/post", "@container": "@index"},
+			"authors": {"@id": "http://example.org/author", "@container": "@id"},
+			"things": {"@id": "http://example.org/thing", "@container": "@type"}
+		}`,
+		out: `{
+			"@context": {
+				"@vocab": "http://example.org/",
+				"title": {"@id": "http://example.org/title", "@container": "@language"},
+				"posts": {"@id": "http://example.org/post", "@container": "@index"},
+				"authors": {"@id": "http://example.org/author", "@container": "@id"},
+				"things": {"@id": "http://example.org/thing", "@container": "@type", "@type": "@id"}
+			},
+			"title": {"en": "The Title", "fr": "Le Titre", "@none": "Title"},
+			"posts": {
+				"first": {"@id": "http://example.org/posts/1", "body": "Hello"},
+				"@none": {"@id": "http://example.org/posts/2"}
+			},
+			"authors": {
+				"http://example.org/alice": {"name": "Alice"}
+			},
+			"things": {
+				"Book": "http://example.org/book",
+				"Film": {"@id": "http://example.org/film", "@type": "Thing"}
+			}
+		}`,
+	},
+	{
+		name: "JSON literal",
+		in: `[{
+			"@id": "http://example.org/s",
+			"http://example.org/config": [{
+				"@value": {"b": [1, 1e21, 0.5], "a": null, "c": "x"},
+				"@type": "@json"
+			}]
+		}]`,
+		ctx: `{
+			"@vocab": "http://example.org/",
+			"config": {"@id": "http://example.org/config", "@type": "@json"}
+		}`,
+		out: exampleJSONLiteral,
+	},
+	{
+		name: "scoped contexts",
+		in: exampleScopedContextsExpanded,
+		ctx: `{
+			"@vocab": "http://example.org/",
+			"author": {
+				"@id": "http://example.org/author",
+				"@context": {"name": "http://xmlns.com/foaf/0.1/name"}
+			},
+			"reviewer": {
+				"@id": "http://example.org/reviewer",
+				"@context": {"@propagate": false, "name": "http://schema.org/name"}
+			},
+			"Book": {
+				"@id": "http://example.org/Book",
+				"@context": {"title": "http://purl.org/dc/terms/title"}
+			}
+		}`,
+		out: exampleScopedContexts,
+	},
+	{
+		name: "nest",
+		in: `[{
+			"@id": "http://example.org/resource",
+			"http://example.org/name": [{"@value": "Resource"}],
+			"http://example.org/mainLabel": [{"@value": "Main label"}],
+			"http://example.org/otherLabel": [{"@value": "Other label"}]
+		}]`,
+		ctx: `{
+			"@vocab": "http://example.org/",
+			"labels": "@nest",
+			"main_label": {"@id": "http://example.org/mainLabel", "@nest": "labels"},
+			"other_label": {"@id": "http://example.org/otherLabel", "@nest": "labels"}
+		}`,
+		out: exampleNest,
+	},
+	{
+		name: "keyword aliases",
+		in: `{
+			"@id": "http://example.org/a",
+			"@type": ["http://example.org/T"],
+			"http://example.org/label": [{"@value": "label", "@language": "en"}]
+		}`,
+		ctx: `{
+			"ex": "http://example.org/",
+			"id": "@id",
+			"value": "@value",
+			"lang": "@language",
+			"@type": {"@container": "@set"}
+		}`,
+		out: `{
+			"@context": {
+				"ex": "http://example.org/",
+				"id": "@id",
+				"value": "@value",
+				"lang": "@language",
+				"@type": {"@container": "@set"}
+			},
+			"id": "ex:a",
+			"@type": ["ex:T"],
+			"ex:label": {"value": "label", "lang": "en"}
+		}`,
+	},
+	{
+		name: "reverse",
+		in: `{
+			"@id": "http://example.org/alice",
+			"@reverse": {
+				"http://example.org/parent": [
+					{"@id": "http://example.org/bob"},
+					{"@id": "http://example.org/carol"}
+				],
+				"http://example.org/child": [{"@id": "http://example.org/dave"}]
+			}
+		}`,
+		ctx: `{
+			"ex": "http://example.org/",
+			"children": {"@reverse": "ex:parent", "@type": "@id"}
+		}`,
+		out: `{
+			"@context": {
+				"ex": "http://example.org/",
+				"children": {"@reverse": "http://example.org/parent", "@type": "@id"}
+			},
+			"@id": "ex:alice",
+			"children": ["ex:bob", "ex:carol"],
+			"@reverse": {
+				"ex:child": {"@id": "ex:dave"}
+			}
+		}`,
+	},
+}
+
+func TestCompact_relative(t *testing.T) {
+	var in, want interface{}
+	err := json.Unmarshal([]byte(`[{
+		"@id": "http://example.org/people/alice",
+		"http://schema.org/knows": [{"@id": "http://example.org/bots/bob"}],
+		"http://schema.org/sameAs": [{"@id": "https://example.com/alice"}]
+	}]`), &in)
+	if err != nil {
+		t.Fatalf("json.Unmarshal(in) = %v", err)
+	}
+	err = json.Unmarshal([]byte(`{
+		"@context": {"@vocab": "http://schema.org/"},
+		"@id": "alice",
+		"knows": {"@id": "../bots/bob"},
+		"sameAs": {"@id": "https://example.com/alice"}
+	}`), &want)
+	if err != nil {
+		t.Fatalf("json.Unmarshal(out) = %v", err)
+	}
+
+	ctx := &Context{Vocab: "http://schema.org/"}
+	opts := &Options{Base: "http://example.org/people/"}
+	out, err := Compact(in, ctx, opts)
+	if err != nil {
+		t.Fatalf("Compact() = %v", err)
+	}
+	if got := roundTripJSON(t, out); !reflect.DeepEqual(got, want) {
+		b, _ := json.Marshal(out)
+		t.Errorf("Compact() = %v, want %v", string(b), want)
+	}
+
+	err = json.Unmarshal([]byte(`{
+		"@context": {"@vocab": "http://schema.org/"},
+		"@id": "http://example.org/people/alice",
+		"knows": {"@id": "http://example.org/bots/bob"},
+		"sameAs": {"@id": "https://example.com/alice"}
+	}`), &want)
+	if err != nil {
+		t.Fatalf("json.Unmarshal(out) = %v", err)
+	}
+
+	opts.KeepAbsoluteIRIs = true
+	out, err = Compact(in, ctx, opts)
+	if err != nil {
+		t.Fatalf("Compact() = %v", err)
+	}
+	if got := roundTripJSON(t, out); !reflect.DeepEqual(got, want) {
+		b, _ := json.Marshal(out)
+		t.Errorf("Compact(KeepAbsoluteIRIs) = %v, want %v", string(b), want)
+	}
+}
+
+func TestCompact_processingMode(t *testing.T) {
+	var in interface{}
+	err := json.Unmarshal([]byte(`[{
+		"http://example.org/p": [{"@value": "x"}]
+	}]`), &in)
+	if err != nil {
+		t.Fatalf("json.Unmarshal(in) = %v", err)
+	}
+	ctx := &Context{
+		Terms: map[string]*Resource{
+			"p": {ID: "http://example.org/p", Props: Props{"@container": {"@index"}}},
+		},
+	}
+
+	tests := map[string]string{
+		"json-ld-1.1": `{"@context": {"p": {"@id": "http://example.org/p", "@container": "@index"}}, "p": {"@none": "x"}}`,
+		"json-ld-1.0": `{"@context": {"p": {"@id": "http://example.org/p", "@container": "@index"}}, "http://example.org/p": "x"}`,
+	}
+	for mode, s := range tests {
+		var want interface{}
+		if err := json.Unmarshal([]byte(s), &want); err != nil {
+			t.Fatalf("json.Unmarshal(out) = %v", err)
+		}
+		out, err := Compact(in, ctx, &Options{ProcessingMode: mode})
+		if err != nil {
+			t.Fatalf("%v: Compact() = %v", mode, err)
+		}
+		if got := roundTripJSON(t, out); !reflect.DeepEqual(got, want) {
+			b, _ := json.Marshal(out)
+			t.Errorf("%v: Compact() = %v, want %v", mode, string(b), s)
+		}
+	}
+}
+
+func parseTestContext(t *testing.T, s string) *Context {
+	var raw interface{}
+	if err := json.Unmarshal([]byte(s), &raw); err != nil {
+		t.Fatalf("json.Unmarshal(ctx) = %v", err)
+	}
+	ctx, err := newProcessor(nil).parseContext(nil, raw)
+	if err != nil {
+		t.Fatalf("parseContext() = %v", err)
+	}
+	return ctx
+}
+
+func TestCompact(t *testing.T) {
+	for _, test := range compactTests {
+		var in, want interface{}
+		if err := json.Unmarshal([]byte(test.in), &in); err != nil {
+			t.Fatalf("%v: json.Unmarshal(in) = %v", test.name, err)
+		}
+		if err := json.Unmarshal([]byte(test.out), &want); err != nil {
+			t.Fatalf("%v: json.Unmarshal(out) = %v", test.name, err)
+		}
+		ctx := parseTestContext(t, test.ctx)
+
+		// Compaction must be deterministic
+		for i := 0; i < 10; i++ {
+			out, err := Compact(in, ctx, nil)
+			if err != nil {
+				t.Errorf("%v: Compact() = %v", test.name, err)
+				break
+			}
+
+			got := roundTripJSON(t, out)
+			if !reflect.DeepEqual(got, want) {
+				b, _ := json.Marshal(out)
+				t.Errorf("%v: Compact() = %v, want %v", test.name, string(b), test.out)
+				break
+			}
+		}
+	}
+}
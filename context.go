@@ -1,86 +1,1149 @@
 package jsonld
 
 import (
+	"reflect"
+	"regexp"
+	"sort"
 	"strings"
 )
 
+// Context is a JSON-LD context.
+//
+// Term definitions are stored as resources: the resource ID is the IRI mapped
+// to the term, and the resource properties contain the term definition
+// keywords. The type mapping is stored in the rdf:type property, other
+// keywords (such as @container or @language) are stored as-is. A nil term
+// definition maps the term to null. A term whose ID is a keyword, such as
+// "@id", is an alias for that keyword.
+//
+// A term definition can contain a scoped context in its @context property,
+// either as a *Context or as its JSON-LD representation. Scoped contexts are
+// applied to the values of the term, or to nodes which have the term as type.
+//
+// A term definition whose @protected property is true can't be redefined by
+// later contexts, except by property-scoped contexts.
 type Context struct {
 	URL string
-	Lang string
+	Lang string // Default language.
+	Direction string // Default base direction, "ltr" or "rtl".
 	Base string // Base URI to resolve relative URIs.
 	Vocab string // Base vocabulary.
 	Terms map[string]*Resource
+
+	// previous is the context to revert to when entering a new node object,
+	// if this context isn't propagated.
+	previous *Context
+	// jsonLD11 is set if the context definition contained JSON-LD 1.1 entries
+	// which aren't reflected in the other fields, such as @version or @import.
+	jsonLD11 bool
+	// nullLang, nullDirection and nullVocab are set if the context definition
+	// explicitly set the default language, base direction or vocabulary to
+	// null, in which case the inherited value is cleared by newChild.
+	nullLang, nullDirection, nullVocab bool
 }
 
+func (ctx *Context) clone() *Context {
+	c := new(Context)
+	if ctx != nil {
+		*c = *ctx
+	}
+	c.Terms = make(map[string]*Resource, len(c.Terms))
+	if ctx != nil {
+		for k, v := range ctx.Terms {
+			c.Terms[k] = v
+		}
+	}
+	return c
+}
+
+// newChild returns a new context containing the definitions of child, and the
+// definitions of ctx not overridden by child. Protected terms of ctx are kept
+// if child contains an identical definition.
 func (ctx *Context) newChild(child *Context) *Context {
+	c := ctx.clone()
 	if child == nil {
-		child = new(Context)
+		return c
 	}
-	if child.Terms == nil {
-		child.Terms = make(map[string]*Resource)
+
+	c.URL = child.URL
+	if child.jsonLD11 {
+		c.jsonLD11 = true
 	}
-	if ctx != nil {
-		if child.Lang == "" {
-			child.Lang = ctx.Lang
+	if child.Lang != "" || child.nullLang {
+		c.Lang, c.nullLang = child.Lang, child.nullLang
+	}
+	if child.Direction != "" || child.nullDirection {
+		c.Direction, c.nullDirection = child.Direction, child.nullDirection
+	}
+	if child.Base != "" {
+		c.Base = child.Base
+	}
+	if child.Vocab != "" || child.nullVocab {
+		c.Vocab, c.nullVocab = child.Vocab, child.nullVocab
+	}
+	for k, v := range child.Terms {
+		if previous := c.Terms[k]; termIsProtected(previous) && sameTermDefinition(previous, v) {
+			continue
+		}
+		c.Terms[k] = v
+	}
+	return c
+}
+
+// expand expands a vocabulary-relative IRI.
+func (ctx *Context) expand(u string) string {
+	if ctx == nil {
+		return u
+	}
+	if iri := expandIRI(ctx, u, false, true); iri != "" {
+		return iri
+	}
+	return u
+}
+
+func termType(term *Resource) string {
+	if term == nil {
+		return ""
+	}
+	t, _ := term.Props.Get(propType).(string)
+	return t
+}
+
+// termLanguage returns the language mapping of a term definition. ok is false
+// if the term definition doesn't have a language mapping, lang is empty if the
+// term is mapped to the null language.
+func termLanguage(term *Resource) (lang string, ok bool) {
+	if term == nil {
+		return "", false
+	}
+	values, ok := term.Props["@language"]
+	if !ok || len(values) == 0 {
+		return "", false
+	}
+	lang, _ = values[0].(string)
+	return lang, true
+}
+
+// termIsReverse checks whether a term definition is a reverse property.
+func termIsReverse(term *Resource) bool {
+	if term == nil {
+		return false
+	}
+	v, _ := term.Props.Get("@reverse").(bool)
+	return v
+}
+
+// termDirection returns the direction mapping of a term definition. ok is
+// false if the term definition doesn't have a direction mapping, dir is empty
+// if the term is mapped to the null direction.
+func termDirection(term *Resource) (dir string, ok bool) {
+	if term == nil {
+		return "", false
+	}
+	values, ok := term.Props["@direction"]
+	if !ok || len(values) == 0 {
+		return "", false
+	}
+	dir, _ = values[0].(string)
+	return dir, true
+}
+
+// termContext returns the scoped context of a term definition.
+func termContext(term *Resource) (ctx interface{}, ok bool) {
+	if term == nil {
+		return nil, false
+	}
+	values, ok := term.Props["@context"]
+	if !ok || len(values) == 0 {
+		return nil, false
+	}
+	return values[0], true
+}
+
+// termNest returns the nest value of a term definition: the term of the
+// nested object properties are grouped in when compacting. It returns an
+// empty string if the term isn't nested.
+func termNest(term *Resource) string {
+	if term == nil {
+		return ""
+	}
+	nest, _ := term.Props.Get("@nest").(string)
+	return nest
+}
+
+func isDirection(v interface{}) bool {
+	return v == "ltr" || v == "rtl"
+}
+
+// termMapContainer returns the map container of a term definition: @language,
+// @index, @id or @type. It returns an empty string if the term doesn't have a
+// map container.
+func termMapContainer(term *Resource) string {
+	for _, c := range []string{"@language", "@index", "@id", "@type"} {
+		if termHasContainer(term, c) {
+			return c
+		}
+	}
+	return ""
+}
+
+func termHasContainer(term *Resource, container string) bool {
+	if term == nil {
+		return false
+	}
+	for _, v := range term.Props["@container"] {
+		if v == container {
+			return true
+		}
+	}
+	return false
+}
+
+// termIsPrefix checks whether a term can be used as a prefix in compact IRIs.
+func termIsPrefix(term *Resource) bool {
+	if term == nil || term.ID == "" {
+		return false
+	}
+	if v, ok := term.Props.Get("@prefix").(bool); ok {
+		return v
+	}
+	return endsWithGenDelim(term.ID) || isBlankNodeID(term.ID)
+}
+
+// termIsProtected checks whether a term definition is protected, ie. whether
+// it can't be redefined by a later context.
+func termIsProtected(term *Resource) bool {
+	if term == nil {
+		return false
+	}
+	v, _ := term.Props.Get("@protected").(bool)
+	return v
+}
+
+// sameTermDefinition checks whether two term definitions are identical,
+// ignoring their @protected flag.
+func sameTermDefinition(a, b *Resource) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if a.ID != b.ID {
+		return false
+	}
+	for k, v := range a.Props {
+		if k != "@protected" && !reflect.DeepEqual(v, b.Props[k]) {
+			return false
 		}
-		if child.Base == "" {
-			child.Base = ctx.Base
+	}
+	for k, v := range b.Props {
+		if _, ok := a.Props[k]; !ok && k != "@protected" && len(v) > 0 {
+			return false
 		}
-		if child.Vocab == "" {
-			child.Vocab = ctx.Vocab
+	}
+	return true
+}
+
+// termRequiresJSONLD11 checks whether the definition of the term k uses
+// features introduced in JSON-LD 1.1.
+func termRequiresJSONLD11(k string, term *Resource) bool {
+	if k == "@type" {
+		return true
+	}
+	if term == nil {
+		return false
+	}
+	for _, kw := range []string{"@context", "@direction", "@nest", "@prefix", "@protected"} {
+		if _, ok := term.Props[kw]; ok {
+			return true
 		}
+	}
+	if t := termType(term); t == "@json" || t == "@none" {
+		return true
+	}
+	containers := term.Props["@container"]
+	if len(containers) > 1 {
+		return true
+	}
+	for _, c := range containers {
+		if c == "@id" || c == "@type" || c == "@graph" {
+			return true
+		}
+	}
+	return false
+}
 
-		for k, v := range ctx.Terms {
-			if _, ok := child.Terms[k]; !ok {
-				child.Terms[k] = v
+// hasProtectedTerms checks whether a context contains protected terms.
+func hasProtectedTerms(ctx *Context) bool {
+	for _, term := range ctx.Terms {
+		if termIsProtected(term) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkProtectedTerms checks that child doesn't redefine protected terms of
+// ctx, unless overrideProtected is set.
+func checkProtectedTerms(ctx, child *Context, overrideProtected bool) error {
+	if overrideProtected || child == nil {
+		return nil
+	}
+	for k, term := range child.Terms {
+		previous, ok := ctx.Terms[k]
+		if ok && termIsProtected(previous) && !sameTermDefinition(previous, term) {
+			return errorf("protected term redefinition", "%q", k)
+		}
+	}
+	return nil
+}
+
+func endsWithGenDelim(iri string) bool {
+	return iri != "" && strings.ContainsAny(iri[len(iri)-1:], ":/?#[]@")
+}
+
+var keywords = map[string]bool{
+	"@base": true,
+	"@container": true,
+	"@context": true,
+	"@default": true,
+	"@direction": true,
+	"@embed": true,
+	"@explicit": true,
+	"@graph": true,
+	"@id": true,
+	"@import": true,
+	"@included": true,
+	"@index": true,
+	"@json": true,
+	"@language": true,
+	"@list": true,
+	"@nest": true,
+	"@none": true,
+	"@omitDefault": true,
+	"@prefix": true,
+	"@preserve": true,
+	"@propagate": true,
+	"@protected": true,
+	"@requireAll": true,
+	"@reverse": true,
+	"@set": true,
+	"@type": true,
+	"@value": true,
+	"@version": true,
+	"@vocab": true,
+}
+
+func isKeyword(s string) bool {
+	return keywords[s]
+}
+
+var keywordRegexp = regexp.MustCompile(`^@[a-zA-Z]+$`)
+
+// looksLikeKeyword checks whether s has the form of a keyword. Such strings
+// are reserved for future use and are ignored.
+func looksLikeKeyword(s string) bool {
+	return keywordRegexp.MatchString(s)
+}
+
+// isCompactIRILike checks whether a term contains a slash, or a colon anywhere
+// but as its first or last character.
+func isCompactIRILike(s string) bool {
+	return strings.Contains(s, "/") || (len(s) > 2 && strings.Contains(s[1:len(s)-1], ":"))
+}
+
+func isBlankNodeID(s string) bool {
+	return strings.HasPrefix(s, "_:")
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// expandIRI expands a string which may be a keyword, a term, a compact IRI or
+// a relative IRI. It returns an empty string if the value expands to null.
+func expandIRI(ctx *Context, value string, documentRelative, vocab bool) string {
+	if isKeyword(value) {
+		return value
+	}
+	if looksLikeKeyword(value) {
+		return ""
+	}
+
+	term, ok := ctx.Terms[value]
+	if ok && term != nil && isKeyword(term.ID) {
+		return term.ID
+	}
+	if vocab && ok {
+		if term == nil {
+			return ""
+		}
+		return term.ID
+	}
+
+	if i := strings.IndexByte(value, ':'); i > 0 {
+		prefix, suffix := value[:i], value[i+1:]
+		if prefix == "_" || strings.HasPrefix(suffix, "//") {
+			return value
+		}
+		if term := ctx.Terms[prefix]; termIsPrefix(term) {
+			return term.ID + suffix
+		}
+		if IsAbsoluteIRI(value) {
+			return value
+		}
+	}
+
+	if vocab && ctx.Vocab != "" {
+		return ctx.Vocab + value
+	}
+	if documentRelative {
+		return ResolveIRI(ctx.Base, value)
+	}
+	return value
+}
+
+// parseContext processes a local context and returns the resulting active
+// context, as defined in
+// https://www.w3.org/TR/json-ld11-api/#context-processing-algorithm.
+func (p *processor) parseContext(active *Context, v interface{}) (*Context, error) {
+	return p.parseScopedContext(active, v, contextOptions{propagate: true})
+}
+
+// contextOptions contains options for context processing.
+type contextOptions struct {
+	// propagate is the default value of @propagate: if false, the resulting
+	// context doesn't apply to nested node objects.
+	propagate bool
+	// overrideProtected allows protected terms to be redefined, for
+	// property-scoped contexts.
+	overrideProtected bool
+}
+
+// Options for property-scoped and type-scoped contexts
+var (
+	propertyScope = contextOptions{propagate: true, overrideProtected: true}
+	typeScope = contextOptions{propagate: false}
+)
+
+type scopedContextKey struct {
+	active *Context
+	term *Resource
+	opts contextOptions
+}
+
+// applyScopedContext applies the scoped context of a term definition to ctx.
+// The resulting contexts are cached, so that nodes using the same term share
+// the same context.
+func (p *processor) applyScopedContext(ctx *Context, term *Resource, opts contextOptions) (*Context, error) {
+	scoped, ok := termContext(term)
+	if !ok {
+		return ctx, nil
+	}
+
+	k := scopedContextKey{ctx, term, opts}
+	if result, ok := p.scopedContexts[k]; ok {
+		return result, nil
+	}
+	result, err := p.parseScopedContext(ctx, scoped, opts)
+	if err != nil {
+		return nil, err
+	}
+	p.scopedContexts[k] = result
+	return result, nil
+}
+
+// parseScopedContext processes a local context with the specified options.
+func (p *processor) parseScopedContext(active *Context, v interface{}, opts contextOptions) (*Context, error) {
+	result := active.clone()
+	propagate := opts.propagate
+
+	if m, ok := v.(map[string]interface{}); ok {
+		if v, ok := m["@propagate"]; ok {
+			b, ok := v.(bool)
+			if !ok {
+				return nil, errorf("invalid @propagate value", "")
+			}
+			propagate = b
+		}
+	}
+	if !propagate && result.previous == nil {
+		result.previous = active
+	}
+
+	values, ok := v.([]interface{})
+	if !ok {
+		values = []interface{}{v}
+	}
+
+	for _, v := range values {
+		var err error
+		switch v := v.(type) {
+		case nil:
+			if !opts.overrideProtected && hasProtectedTerms(result) {
+				return nil, errorf("invalid context nullification", "")
+			}
+			previous := result.previous
+			result = (&Context{Base: p.opts.Base}).clone()
+			if !propagate {
+				result.previous = previous
+			}
+		case *Context:
+			if err = p.checkChildContext(result, v, opts); err == nil {
+				result = result.newChild(v)
+			}
+		case string:
+			var fetched *Context
+			if fetched, err = p.fetchContext(ResolveIRI(p.opts.Base, v)); err == nil {
+				if err = p.checkChildContext(result, fetched, opts); err == nil {
+					// The base IRI of remote contexts is ignored
+					base := result.Base
+					result = result.newChild(fetched)
+					result.Base = base
+				}
 			}
+		case map[string]interface{}:
+			result, err = p.parseContextMap(result, v, opts.overrideProtected)
+		default:
+			err = errorf("invalid local context", "unexpected %T", v)
+		}
+		if err != nil {
+			return nil, err
 		}
 	}
-	return child
+
+	return result, nil
 }
 
-func (ctx *Context) expand(u string) string {
-	if ctx == nil {
-		return u
+// checkChildContext checks that a context which has already been processed
+// can be applied to the active context.
+func (p *processor) checkChildContext(active, child *Context, opts contextOptions) error {
+	if err := p.checkProcessingMode(child); err != nil {
+		return err
+	}
+	return checkProtectedTerms(active, child, opts.overrideProtected)
+}
+
+func (p *processor) parseContextMap(active *Context, m map[string]interface{}, overrideProtected bool) (*Context, error) {
+	result := active.clone()
+	result.URL = ""
+
+	if v, ok := m["@version"]; ok {
+		if v != 1.1 {
+			return nil, errorf("invalid @version value", "%v", v)
+		}
+		if p.isJSONLD10() {
+			return nil, errorf("processing mode conflict", "")
+		}
+	}
+	for _, k := range []string{"@version", "@import", "@direction", "@propagate", "@protected"} {
+		if _, ok := m[k]; !ok {
+			continue
+		}
+		if p.isJSONLD10() && k != "@version" {
+			return nil, errorf("invalid context entry", "%q requires JSON-LD 1.1", k)
+		}
+		result.jsonLD11 = true
+	}
+
+	if v, ok := m["@import"]; ok {
+		imported, err := p.importContext(v)
+		if err != nil {
+			return nil, err
+		}
+		// Entries of the local context override the imported ones
+		merged := make(map[string]interface{}, len(imported)+len(m))
+		for k, v := range imported {
+			merged[k] = v
+		}
+		for k, v := range m {
+			if k != "@import" {
+				merged[k] = v
+			}
+		}
+		m = merged
 	}
 
-	if i := strings.IndexRune(u, ':'); i >= 0 {
-		// It's either an absolute or a relative URI
-		prefix, suffix := u[:i], u[i+1:]
-		if term, ok := ctx.Terms[prefix]; ok && term != nil {
-			return term.ID + suffix // Relative
+	protected := false
+	if v, ok := m["@protected"]; ok {
+		b, ok := v.(bool)
+		if !ok {
+			return nil, errorf("invalid @protected value", "")
+		}
+		protected = b
+	}
+
+	if v, ok := m["@base"]; ok {
+		switch v := v.(type) {
+		case nil:
+			result.Base = ""
+		case string:
+			if IsAbsoluteIRI(v) {
+				result.Base = v
+			} else if result.Base != "" {
+				result.Base = ResolveIRI(result.Base, v)
+			} else {
+				return nil, errorf("invalid base IRI", "%q", v)
+			}
+		default:
+			return nil, errorf("invalid base IRI", "")
+		}
+	}
+
+	if v, ok := m["@vocab"]; ok {
+		switch v := v.(type) {
+		case nil:
+			result.Vocab, result.nullVocab = "", true
+		case string:
+			// JSON-LD 1.0 doesn't support relative vocabulary mappings
+			iri := expandIRI(result, v, !p.isJSONLD10(), true)
+			if !IsAbsoluteIRI(iri) && !isBlankNodeID(iri) {
+				return nil, errorf("invalid vocab mapping", "%q", v)
+			}
+			result.Vocab, result.nullVocab = iri, false
+		default:
+			return nil, errorf("invalid vocab mapping", "")
+		}
+	}
+
+	if v, ok := m["@language"]; ok {
+		switch v := v.(type) {
+		case nil:
+			result.Lang, result.nullLang = "", true
+		case string:
+			result.Lang, result.nullLang = strings.ToLower(v), false
+		default:
+			return nil, errorf("invalid default language", "")
+		}
+	}
+
+	if v, ok := m["@direction"]; ok {
+		switch {
+		case v == nil:
+			result.Direction, result.nullDirection = "", true
+		case isDirection(v):
+			result.Direction, result.nullDirection = v.(string), false
+		default:
+			return nil, errorf("invalid base direction", "%v", v)
+		}
+	}
+
+	defined := make(map[string]bool)
+	for _, k := range sortedKeys(m) {
+		switch k {
+		case "@base", "@vocab", "@language", "@direction", "@propagate", "@protected", "@version":
+			continue
+		}
+		if err := p.parseTermDefinition(result, m, k, defined, protected, overrideProtected); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// parseTermDefinition creates a term definition in the active context, as
+// defined in https://www.w3.org/TR/json-ld11-api/#create-term-definition.
+// protected is the default value of @protected. Protected terms of the active
+// context can only be redefined if overrideProtected is set or if the new
+// definition is identical.
+func (p *processor) parseTermDefinition(active *Context, local map[string]interface{}, k string, defined map[string]bool, protected, overrideProtected bool) error {
+	if _, ok := defined[k]; ok {
+		return p.createTermDefinition(active, local, k, defined, protected, overrideProtected)
+	}
+
+	previous, hasPrevious := active.Terms[k]
+	if err := p.createTermDefinition(active, local, k, defined, protected, overrideProtected); err != nil {
+		return err
+	}
+	if !hasPrevious || !termIsProtected(previous) || overrideProtected {
+		return nil
+	}
+
+	term, ok := active.Terms[k]
+	if !ok || !sameTermDefinition(previous, term) {
+		return errorf("protected term redefinition", "%q", k)
+	}
+	// Keep the protected definition
+	active.Terms[k] = previous
+	return nil
+}
+
+func (p *processor) createTermDefinition(active *Context, local map[string]interface{}, k string, defined map[string]bool, protected, overrideProtected bool) error {
+	if done, ok := defined[k]; ok {
+		if done {
+			return nil
+		}
+		return errorf("cyclic IRI mapping", "%q", k)
+	}
+
+	if k == "" {
+		return errorf("invalid term definition", "empty term")
+	}
+	if k == "@type" && !p.isJSONLD10() {
+		return parseTypeTermDefinition(active, local[k], defined, protected)
+	}
+	if isKeyword(k) {
+		return errorf("keyword redefinition", "%q", k)
+	}
+	if looksLikeKeyword(k) {
+		return nil
+	}
+
+	defined[k] = false
+	delete(active.Terms, k)
+
+	var m map[string]interface{}
+	simple := false
+	switch v := local[k].(type) {
+	case nil:
+		m = map[string]interface{}{"@id": nil}
+	case string:
+		m = map[string]interface{}{"@id": v}
+		simple = true
+	case map[string]interface{}:
+		m = v
+	default:
+		return errorf("invalid term definition", "%q", k)
+	}
+
+	// Make sure prefixes and terms referenced by this definition are defined
+	// first
+	expand := func(s string, vocab bool) (string, error) {
+		if _, ok := local[s]; ok {
+			if err := p.parseTermDefinition(active, local, s, defined, protected, overrideProtected); err != nil {
+				return "", err
+			}
+		}
+		if i := strings.IndexByte(s, ':'); i > 0 {
+			if _, ok := local[s[:i]]; ok {
+				if err := p.parseTermDefinition(active, local, s[:i], defined, protected, overrideProtected); err != nil {
+					return "", err
+				}
+			}
+		}
+		return expandIRI(active, s, false, vocab), nil
+	}
+
+	term := &Resource{Props: make(Props)}
+
+	if v, ok := m["@protected"]; ok {
+		b, ok := v.(bool)
+		if !ok {
+			return errorf("invalid @protected value", "%q", k)
+		}
+		protected = b
+	}
+	if protected {
+		term.Props.Set("@protected", true)
+	}
+
+	for _, kw := range sortedKeys(m) {
+		switch kw {
+		case "@id", "@reverse", "@type", "@container", "@language", "@direction", "@nest", "@prefix", "@context", "@protected":
+		default:
+			return errorf("invalid term definition", "%q has unsupported key %q", k, kw)
+		}
+	}
+	if p.isJSONLD10() {
+		for _, kw := range []string{"@context", "@direction", "@nest", "@prefix", "@protected"} {
+			if _, ok := m[kw]; ok {
+				return errorf("invalid term definition", "%q: %q requires JSON-LD 1.1", k, kw)
+			}
+		}
+	}
+
+	if v, ok := m["@type"]; ok {
+		t, ok := v.(string)
+		if !ok {
+			return errorf("invalid type mapping", "%q", k)
+		}
+		if t == "@json" && p.isJSONLD10() {
+			return errorf("invalid type mapping", "%q: @json requires JSON-LD 1.1", k)
+		}
+		if t != "@id" && t != "@vocab" && t != "@json" {
+			var err error
+			if t, err = expand(t, true); err != nil {
+				return err
+			}
+			if !IsAbsoluteIRI(t) || isBlankNodeID(t) {
+				return errorf("invalid type mapping", "%q", k)
+			}
+		}
+		term.Props.Set(propType, t)
+	}
+
+	if v, ok := m["@reverse"]; ok {
+		if _, ok := m["@id"]; ok {
+			return errorf("invalid reverse property", "%q has both @id and @reverse", k)
+		}
+		id, ok := v.(string)
+		if !ok {
+			return errorf("invalid IRI mapping", "%q", k)
+		}
+		if !isKeyword(id) && looksLikeKeyword(id) {
+			defined[k] = true
+			return nil
+		}
+
+		iri, err := expand(id, true)
+		if err != nil {
+			return err
+		}
+		if !IsAbsoluteIRI(iri) && !isBlankNodeID(iri) {
+			return errorf("invalid IRI mapping", "%q", k)
+		}
+		term.ID = iri
+
+		if v, ok := m["@container"]; ok {
+			switch v {
+			case nil:
+			case "@set", "@index":
+				term.Props.Set("@container", v)
+			default:
+				return errorf("invalid reverse property", "%q has an invalid container", k)
+			}
+		}
+
+		term.Props.Set("@reverse", true)
+		active.Terms[k] = term
+		defined[k] = true
+		return nil
+	}
+
+	if v, ok := m["@id"]; ok && v != k {
+		if v == nil {
+			// The term is explicitly mapped to null
+			active.Terms[k] = nil
+			defined[k] = true
+			return nil
+		}
+		id, ok := v.(string)
+		if !ok {
+			return errorf("invalid IRI mapping", "%q", k)
+		}
+		if !isKeyword(id) && looksLikeKeyword(id) {
+			defined[k] = true
+			return nil
+		}
+
+		iri, err := expand(id, true)
+		if err != nil {
+			return err
+		}
+		if iri == "@context" || (!isKeyword(iri) && !IsAbsoluteIRI(iri) && !isBlankNodeID(iri)) {
+			return errorf("invalid IRI mapping", "%q", k)
+		}
+		if isCompactIRILike(k) {
+			if _, err := expand(k[:strings.IndexByte(k+":", ':')], true); err != nil {
+				return err
+			}
+			if expanded := expandIRI(active, k, false, true); expanded != iri {
+				return errorf("invalid IRI mapping", "%q", k)
+			}
+		}
+		term.ID = iri
+
+	} else if i := strings.IndexByte(k, ':'); i > 0 {
+		prefix, suffix := k[:i], k[i+1:]
+		if _, ok := local[prefix]; ok {
+			if err := p.parseTermDefinition(active, local, prefix, defined, protected, overrideProtected); err != nil {
+				return err
+			}
+		}
+		if prefixTerm := active.Terms[prefix]; prefixTerm != nil && prefixTerm.ID != "" {
+			term.ID = prefixTerm.ID + suffix
 		} else {
-			return u // Absolute
+			term.ID = k
+		}
+	} else if strings.Contains(k, "/") {
+		term.ID = expandIRI(active, k, false, true)
+		if !IsAbsoluteIRI(term.ID) {
+			return errorf("invalid IRI mapping", "%q", k)
 		}
+	} else if active.Vocab != "" {
+		term.ID = active.Vocab + k
 	} else {
-		if term, ok := ctx.Terms[u]; ok && term != nil {
-			return term.ID
-		} else {
-			return ctx.Vocab + u
+		return errorf("invalid IRI mapping", "%q", k)
+	}
+
+	if v, ok := m["@container"]; ok {
+		values, ok := v.([]interface{})
+		if ok && p.isJSONLD10() {
+			return errorf("invalid container mapping", "%q: multiple containers require JSON-LD 1.1", k)
+		} else if !ok {
+			values = []interface{}{v}
+		}
+		maps := 0
+		for _, c := range values {
+			switch c {
+			case "@id", "@type":
+				if p.isJSONLD10() {
+					return errorf("invalid container mapping", "%q: %v requires JSON-LD 1.1", k, c)
+				}
+				fallthrough
+			case "@language", "@index":
+				maps++
+				fallthrough
+			case "@list", "@set":
+				term.Props.Add("@container", c)
+			default:
+				return errorf("invalid container mapping", "%q", k)
+			}
+		}
+		if termHasContainer(term, "@list") && len(values) > 1 {
+			return errorf("invalid container mapping", "%q", k)
+		}
+		if maps > 1 {
+			return errorf("invalid container mapping", "%q has more than one map container", k)
+		}
+
+		if termHasContainer(term, "@type") {
+			switch termType(term) {
+			case "":
+				term.Props.Set(propType, "@id")
+			case "@id", "@vocab":
+			default:
+				return errorf("invalid type mapping", "%q has a @type container", k)
+			}
+		}
+	}
+
+	if v, ok := m["@language"]; ok {
+		switch v := v.(type) {
+		case nil:
+			term.Props.Set("@language", nil)
+		case string:
+			term.Props.Set("@language", strings.ToLower(v))
+		default:
+			return errorf("invalid language mapping", "%q", k)
 		}
 	}
+
+	if v, ok := m["@direction"]; ok {
+		if v != nil && !isDirection(v) {
+			return errorf("invalid base direction", "%q", k)
+		}
+		term.Props.Set("@direction", v)
+	}
+
+	if v, ok := m["@context"]; ok {
+		// Scoped contexts are processed when used, make sure they're valid
+		if _, err := p.parseScopedContext(active, v, propertyScope); err != nil {
+			if err, ok := err.(*Error); ok {
+				return errorf("invalid scoped context", "%q: %v", k, err.Code)
+			}
+			return err
+		}
+		term.Props.Set("@context", v)
+	}
+
+	if v, ok := m["@nest"]; ok {
+		nest, ok := v.(string)
+		if !ok || (isKeyword(nest) && nest != "@nest") {
+			return errorf("invalid @nest value", "%q", k)
+		}
+		term.Props.Set("@nest", nest)
+	}
+
+	if v, ok := m["@prefix"]; ok {
+		if strings.ContainsAny(k, ":/") {
+			return errorf("invalid term definition", "%q", k)
+		}
+		b, ok := v.(bool)
+		if !ok {
+			return errorf("invalid @prefix value", "%q", k)
+		}
+		if b && isKeyword(term.ID) {
+			return errorf("invalid term definition", "%q", k)
+		}
+		term.Props.Set("@prefix", b)
+	} else if !simple && termIsPrefix(term) {
+		// Only simple term definitions can be used as prefixes by default
+		term.Props.Set("@prefix", false)
+	}
+
+	if len(term.Props) == 0 {
+		term.Props = nil
+	}
+	active.Terms[k] = term
+	defined[k] = true
+	return nil
 }
 
-func (ctx *Context) reduce(u string) (reduced string, term *Resource) {
+// importContext fetches the remote context referenced by an @import entry, and
+// returns its unprocessed definition.
+func (p *processor) importContext(v interface{}) (map[string]interface{}, error) {
+	url, ok := v.(string)
+	if !ok {
+		return nil, errorf("invalid @import value", "")
+	}
+	doc, err := p.fetchDocument(ResolveIRI(p.opts.Base, url))
+	if err != nil {
+		return nil, err
+	}
+	m, _ := doc.(map[string]interface{})
+	imported, ok := m["@context"].(map[string]interface{})
+	if !ok {
+		return nil, errorf("invalid remote context", "%q: @context isn't a map", url)
+	}
+	if _, ok := imported["@import"]; ok {
+		return nil, errorf("invalid context entry", "%q: nested @import", url)
+	}
+	return imported, nil
+}
+
+// parseTypeTermDefinition processes a definition of the @type keyword. It can
+// only be used to set its container to @set and to protect it.
+func parseTypeTermDefinition(active *Context, v interface{}, defined map[string]bool, protected bool) error {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return errorf("keyword redefinition", "%q", "@type")
+	}
+	for kw, v := range m {
+		switch kw {
+		case "@container":
+			if v != "@set" {
+				return errorf("keyword redefinition", "%q", "@type")
+			}
+		case "@protected":
+			b, ok := v.(bool)
+			if !ok {
+				return errorf("invalid @protected value", "%q", "@type")
+			}
+			protected = b
+		default:
+			return errorf("keyword redefinition", "%q", "@type")
+		}
+	}
+
+	term := &Resource{Props: make(Props)}
+	if _, ok := m["@container"]; ok {
+		term.Props.Set("@container", "@set")
+	}
+	if protected {
+		term.Props.Set("@protected", true)
+	}
+	if len(term.Props) == 0 {
+		term.Props = nil
+	}
+	active.Terms["@type"] = term
+	defined["@type"] = true
+	return nil
+}
+
+func (p *processor) fetchContext(url string) (*Context, error) {
+	if ctx, ok := p.contexts[url]; ok {
+		return ctx, nil
+	}
+	if p.opts.FetchContext == nil {
+		return nil, errorf("loading remote context failed", "fetching remote contexts is disabled")
+	}
+	ctx, err := p.opts.FetchContext(url)
+	if err != nil {
+		return nil, &Error{Code: "loading remote context failed", Message: err.Error()}
+	}
+	p.contexts[url] = ctx
+	return ctx, nil
+}
+
+func (p *processor) fetchDocument(url string) (interface{}, error) {
+	if p.opts.FetchDocument == nil {
+		return nil, errorf("loading remote context failed", "fetching remote documents is disabled")
+	}
+	doc, err := p.opts.FetchDocument(url)
+	if err != nil {
+		return nil, &Error{Code: "loading remote context failed", Message: err.Error()}
+	}
+	return doc, nil
+}
+
+// formatContext converts a context to its JSON-LD representation.
+func formatContext(ctx *Context) interface{} {
 	if ctx == nil {
-		return u, nil
+		return nil
+	}
+	if ctx.URL != "" {
+		return ctx.URL
+	}
+
+	m := make(map[string]interface{})
+
+	if ctx.Lang != "" {
+		m["@language"] = ctx.Lang
+	} else if ctx.nullLang {
+		m["@language"] = nil
+	}
+	if ctx.Direction != "" {
+		m["@direction"] = ctx.Direction
+	} else if ctx.nullDirection {
+		m["@direction"] = nil
+	}
+	if ctx.Base != "" {
+		m["@base"] = ctx.Base
+	}
+	if ctx.Vocab != "" {
+		m["@vocab"] = ctx.Vocab
+	} else if ctx.nullVocab {
+		m["@vocab"] = nil
 	}
 
 	for k, term := range ctx.Terms {
-		if len(k) > 0 && k[0] == '@' {
+		m[k] = formatTermDefinition(term)
+	}
+
+	return m
+}
+
+func formatTermDefinition(term *Resource) interface{} {
+	if term == nil {
+		return nil
+	}
+
+	m := make(map[string]interface{})
+	for k, values := range term.Props {
+		if len(values) == 0 {
 			continue
 		}
-		if term.ID == u {
-			return k, term
-		}
-		if term.ID != "" && strings.HasPrefix(u, term.ID) {
-			return k + ":" + strings.TrimPrefix(u, term.ID), nil
+		switch k {
+		case propType:
+			m["@type"] = values[0]
+		case "@container":
+			if len(values) == 1 {
+				m[k] = values[0]
+			} else {
+				m[k] = values
+			}
+		case "@context":
+			if ctx, ok := values[0].(*Context); ok {
+				m[k] = formatContext(ctx)
+			} else {
+				m[k] = values[0]
+			}
+		case "@prefix", "@reverse":
+			// Handled below
+		default:
+			m[k] = values[0]
 		}
 	}
 
-	if ctx.Vocab != "" && strings.HasPrefix(u, ctx.Vocab) {
-		return strings.TrimPrefix(u, ctx.Vocab), nil
+	if termIsReverse(term) {
+		m["@reverse"] = term.ID
+		return m
 	}
 
-	return u, nil
+	prefix, hasPrefix := term.Props.Get("@prefix").(bool)
+	if len(m) == 0 && term.ID != "" && (!hasPrefix || prefix == termIsPrefix(&Resource{ID: term.ID})) {
+		return term.ID
+	}
+	if prefix {
+		m["@prefix"] = true
+	}
+	if term.ID != "" {
+		m["@id"] = term.ID
+	}
+	return m
 }
@@ -0,0 +1,12 @@
+package jsonld
+
+// Graph is a set of nodes.
+type Graph []*Resource
+
+// Dataset is a collection of graphs: a default graph, and zero or more named
+// graphs.
+type Dataset struct {
+	Default Graph
+	// Named maps graph names to named graphs.
+	Named map[string]Graph
+}
@@ -7,6 +7,7 @@ import (
 	"io"
 	"net/http"
 	"reflect"
+	"strconv"
 )
 
 // FetchContextFunc fetches remote contexts.
@@ -14,6 +15,29 @@ type FetchContextFunc func(url string) (*Context, error)
 
 // FetchContext fetches remote contexts with http.DefaultClient.
 func FetchContext(url string) (*Context, error) {
+	doc, err := FetchDocument(url)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := doc.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("jsonld: remote context document isn't a JSON object")
+	}
+
+	ctx, err := newProcessor(nil).parseContext(nil, m["@context"])
+	if err != nil {
+		return nil, err
+	}
+	ctx.URL = url
+	return ctx, nil
+}
+
+// FetchDocumentFunc fetches remote JSON-LD documents. The document is returned
+// as decoded by encoding/json into an interface{} value.
+type FetchDocumentFunc func(url string) (interface{}, error)
+
+// FetchDocument fetches remote JSON-LD documents with http.DefaultClient.
+func FetchDocument(url string) (interface{}, error) {
 	req, err := http.NewRequest(http.MethodGet, url, nil)
 	if err != nil {
 		return nil, err
@@ -27,15 +51,11 @@ func FetchContext(url string) (*Context, error) {
 	}
 	defer resp.Body.Close()
 
-	// TODO: that's ugly, I'm just lazy
-	var data struct {
-		Context interface{} `json:"@context"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+	var doc interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
 		return nil, err
 	}
-
-	return new(Decoder).parseContext(nil, data.Context)
+	return doc, nil
 }
 
 // Decoder decodes JSON-LD values.
@@ -45,6 +65,17 @@ type Decoder struct {
 	// FetchContext, if non-nil, will be called to fetch remote contexts. By
 	// default, remote contexts are not fetched.
 	FetchContext FetchContextFunc
+	// FetchDocument, if non-nil, will be called to fetch the contexts
+	// referenced by @import, which are merged as JSON before being processed.
+	// By default, they are not fetched.
+	FetchDocument FetchDocumentFunc
+	// Base, if non-empty, is the URL of the document. It's used as the base
+	// IRI to resolve relative IRIs.
+	Base string
+	// ProcessingMode is either "json-ld-1.1" (the default) or "json-ld-1.0".
+	// In JSON-LD 1.0 mode, features introduced in JSON-LD 1.1 are rejected
+	// or ignored.
+	ProcessingMode string
 
 	dec *json.Decoder
 }
@@ -55,51 +86,235 @@ func NewDecoder(r io.Reader) *Decoder {
 }
 
 // Decode decodes a JSON-LD value.
+//
+// If v is a *Dataset, the whole dataset is decoded. If v is a pointer to a
+// slice, such as a *Graph, each node of the default graph is decoded into an
+// element of the slice. Otherwise, the document must contain a single node.
 func (d *Decoder) Decode(v interface{}) error {
 	var raw interface{}
 	if err := d.dec.Decode(&raw); err != nil {
 		return err
 	}
 
-	raw, err := d.parse(nil, raw, "")
+	p := d.newProcessor()
+	p.keepUnmapped = true
+	expanded, err := p.expandDocument(raw)
 	if err != nil {
 		return err
 	}
 
+	if ds, ok := v.(*Dataset); ok {
+		parsed, err := d.parseDataset(expanded)
+		if err != nil {
+			return err
+		}
+		*ds = *parsed
+		return nil
+	}
+
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && !rv.IsNil() && rv.Elem().Kind() == reflect.Slice {
+		ds, err := d.parseDataset(expanded)
+		if err != nil {
+			return err
+		}
+		return d.unmarshalGraph(p, d.Context, ds.Default, rv.Elem())
+	}
+
+	var r *Resource
+	switch len(expanded) {
+	case 0:
+		r = new(Resource)
+	case 1:
+		m, _ := expanded[0].(map[string]interface{})
+		if r, err = d.parseResource(m); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("jsonld: expected a single resource, got %v", len(expanded))
+	}
+
+	return d.DecodeResource(r, v)
+}
+
+// DecodeResource stores a resource in the value pointed to by v, using the
+// same rules as Decode.
+func (d *Decoder) DecodeResource(r *Resource, v interface{}) error {
 	rv := reflect.ValueOf(v)
 	if rv.Kind() != reflect.Ptr || rv.IsNil() {
 		return errors.New("jsonld: cannot unmarshal non-pointer")
 	}
 
-	return d.unmarshal(raw, reflect.Indirect(rv))
+	return d.unmarshal(d.newProcessor(), d.Context, r, reflect.Indirect(rv))
 }
 
-func (d *Decoder) parse(ctx *Context, v interface{}, t string) (interface{}, error) {
-	// Type embedded in value
-	m, ok := v.(map[string]interface{})
-	if ok {
-		v = m["@value"]
-		if t == "" {
-			switch rawType := m["@type"].(type) {
-			case string:
-				t = rawType
-			case []interface{}:
-				if len(rawType) > 0 {
-					t, _ = rawType[0].(string)
+func (d *Decoder) newProcessor() *processor {
+	return newProcessor(&Options{
+		Base: d.Base,
+		ExpandContext: d.Context,
+		FetchContext: d.FetchContext,
+		FetchDocument: d.FetchDocument,
+		ProcessingMode: d.ProcessingMode,
+	})
+}
+
+// parseDataset converts an expanded document to a dataset. Nodes with a @graph
+// entry are converted to named graphs, and are also added to the enclosing
+// graph if they have properties.
+func (d *Decoder) parseDataset(expanded []interface{}) (*Dataset, error) {
+	used := make(map[string]bool)
+	collectIDs(expanded, used)
+
+	pd := &datasetParser{
+		d: d,
+		ds: new(Dataset),
+		issuer: NewIdentifierIssuer("_:g"),
+		used: used,
+	}
+	if err := pd.addGraph("", expanded); err != nil {
+		return nil, err
+	}
+	return pd.ds, nil
+}
+
+// collectIDs collects the node identifiers used in an expanded value.
+func collectIDs(v interface{}, ids map[string]bool) {
+	switch v := v.(type) {
+	case []interface{}:
+		for _, item := range v {
+			collectIDs(item, ids)
+		}
+	case map[string]interface{}:
+		if id, ok := v["@id"].(string); ok {
+			ids[id] = true
+		}
+		for k, item := range v {
+			if k != "@value" {
+				collectIDs(item, ids)
+			}
+		}
+	}
+}
+
+type datasetParser struct {
+	d *Decoder
+	ds *Dataset
+	issuer *IdentifierIssuer
+	used map[string]bool
+}
+
+// addGraph adds nodes to the graph name, or to the default graph if name is
+// empty.
+func (pd *datasetParser) addGraph(name string, nodes []interface{}) error {
+	var g Graph
+	for _, v := range nodes {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		r, err := pd.d.parseResource(m)
+		if err != nil {
+			return err
+		}
+
+		// Included nodes are moved to the graph
+		included := r.Included
+		r.Included = nil
+
+		if graph, ok := m["@graph"].([]interface{}); ok {
+			if r.ID == "" {
+				r.ID = pd.issuer.Issue("")
+				for pd.used[r.ID] {
+					r.ID = pd.issuer.Issue("")
 				}
 			}
+			if err := pd.addGraph(r.ID, graph); err != nil {
+				return err
+			}
 		}
+
+		if _, ok := m["@graph"]; !ok || r.Props != nil {
+			g = append(g, r)
+		}
+		g = appendIncluded(g, included)
 	}
 
-	switch t {
-	case "@id":
-		if m != nil {
-			return d.parseResource(ctx, m)
-		} else if s, ok := v.(string); ok {
-			return &Resource{ID: s}, nil
-		} else {
-			return nil, errors.New("jsonld: expected an ID")
+	if name == "" {
+		pd.ds.Default = append(pd.ds.Default, g...)
+	} else {
+		if pd.ds.Named == nil {
+			pd.ds.Named = make(map[string]Graph)
+		}
+		pd.ds.Named[name] = append(pd.ds.Named[name], g...)
+	}
+	return nil
+}
+
+// appendIncluded appends included nodes and the nodes they include to g.
+func appendIncluded(g Graph, included []*Resource) Graph {
+	for _, r := range included {
+		nested := r.Included
+		r.Included = nil
+		g = appendIncluded(append(g, r), nested)
+	}
+	return g
+}
+
+// parse converts an expanded value to a Go value. List objects are converted
+// to List values, and values with an index are wrapped in Indexed.
+func (d *Decoder) parse(m map[string]interface{}) (interface{}, error) {
+	v, err := d.parseValue(m)
+	if err != nil {
+		return nil, err
+	}
+	if index, ok := m["@index"].(string); ok {
+		return Indexed{Index: index, Value: v}, nil
+	}
+	return v, nil
+}
+
+func (d *Decoder) parseValue(m map[string]interface{}) (interface{}, error) {
+	if items, ok := m["@list"].([]interface{}); ok {
+		l := make(List, 0, len(items))
+		for _, item := range items {
+			im, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			v, err := d.parse(im)
+			if err != nil {
+				return nil, err
+			}
+			l = append(l, v)
+		}
+		return l, nil
+	}
+
+	v, ok := m["@value"]
+	if !ok {
+		return d.parseResource(m)
+	}
+
+	if m["@type"] == "@json" {
+		s, err := canonicalJSON(v)
+		if err != nil {
+			return nil, err
+		}
+		return json.RawMessage(s), nil
+	}
+
+	lang, hasLang := m["@language"].(string)
+	dir, hasDir := m["@direction"].(string)
+	if hasLang || hasDir {
+		s, ok := v.(string)
+		if !ok {
+			return nil, errors.New("jsonld: expected a string")
 		}
+		return LangString{Value: s, Language: lang, Direction: dir}, nil
+	}
+
+	t, _ := m["@type"].(string)
+	switch t {
 	case typeString:
 		if s, ok := v.(string); ok {
 			return s, nil
@@ -110,90 +325,92 @@ func (d *Decoder) parse(ctx *Context, v interface{}, t string) (interface{}, err
 		// TODO: big ints are strings, use json.Number(v).Int64()
 		if f, ok := v.(float64); ok {
 			return int64(f), nil
-		} else {
-			return nil, errors.New("jsonld: expected an integer")
+		} else if s, ok := v.(string); ok {
+			if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+				return i, nil
+			}
 		}
+		return nil, errors.New("jsonld: expected an integer")
 	case typeBoolean:
 		if b, ok := v.(bool); ok {
 			return b, nil
-		} else {
-			return nil, errors.New("jsonld: expected a boolean")
+		} else if s, ok := v.(string); ok {
+			switch s {
+			case "true", "1":
+				return true, nil
+			case "false", "0":
+				return false, nil
+			}
 		}
+		return nil, errors.New("jsonld: expected a boolean")
 	case typeDouble:
 		// TODO: big floats are strings, use json.Number(v).Float64()
 		if f, ok := v.(float64); ok {
 			return f, nil
-		} else {
-			return nil, errors.New("jsonld: expected an double")
+		} else if s, ok := v.(string); ok {
+			if f, err := strconv.ParseFloat(s, 64); err == nil {
+				return f, nil
+			}
 		}
+		return nil, errors.New("jsonld: expected an double")
 	case typeAnyURI:
 		if u, ok := v.(string); ok {
-			return ctx.expand(u), nil
+			return u, nil
 		} else {
 			return nil, errors.New("jsonld: expected a URI")
 		}
 	default:
-		if m != nil {
-			return d.parseResource(ctx, m)
-		} else {
-			// No type info, return raw JSON value
-			return v, nil
-		}
+		// No type info, return raw JSON value
+		return v, nil
 	}
 }
 
-func (d *Decoder) parseResource(ctx *Context, m map[string]interface{}) (*Resource, error) {
-	if rawCtx, ok := m["@context"]; ok {
-		var err error
-		if ctx, err = d.parseContext(ctx, rawCtx); err != nil {
-			return nil, err
-		}
-	}
-
+// parseResource converts an expanded node object to a resource.
+func (d *Decoder) parseResource(m map[string]interface{}) (*Resource, error) {
 	n := new(Resource)
 
-	if rawID, ok := m["@id"].(string); ok {
-		n.ID = ctx.expand(rawID)
+	if id, ok := m["@id"].(string); ok {
+		n.ID = id
 	}
 
-	for k, v := range m {
-		if k == "@type" {
-			k = propType
-		}
-
-		if len(k) > 0 && k[0] == '@' {
-			continue
-		}
-
-		var t string
-		if k == propType {
-			t = typeAnyURI
-		} else if ctx != nil {
-			if term, ok := ctx.Terms[k]; ok {
-				if term != nil {
-					if term.ID != "" {
-						k = ctx.expand(term.ID)
-					} else {
-						k = ctx.expand(k)
-					}
-					t, _ = term.Props.Get(propType).(string)
-				} else {
-					// Keep k as-is
+	if reverse, ok := m["@reverse"].(map[string]interface{}); ok {
+		for _, k := range sortedKeys(reverse) {
+			values, _ := reverse[k].([]interface{})
+			for _, v := range values {
+				vm, ok := v.(map[string]interface{})
+				if !ok {
+					continue
 				}
-			} else {
-				k = ctx.expand(k)
+				r, err := d.parseResource(vm)
+				if err != nil {
+					return n, err
+				}
+				if n.Reverse == nil {
+					n.Reverse = make(Props)
+				}
+				n.Reverse.Add(k, r)
 			}
 		}
+	}
 
-		values, ok := v.([]interface{})
-		if !ok {
-			values = []interface{}{v}
+	for _, k := range sortedKeys(m) {
+		values, _ := m[k].([]interface{})
+
+		if k == "@type" {
+			k = propType
+		} else if isKeyword(k) {
+			continue
 		}
 
 		for _, v := range values {
-			vv, err := d.parse(ctx, v, t)
-			if err != nil {
-				return n, err
+			vv := v
+			if m, ok := v.(map[string]interface{}); ok {
+				var err error
+				if vv, err = d.parse(m); err != nil {
+					return n, err
+				}
+			} else if _, ok := v.(string); !ok || k != propType {
+				continue
 			}
 			if n.Props == nil {
 				n.Props = make(Props)
@@ -202,93 +419,235 @@ func (d *Decoder) parseResource(ctx *Context, m map[string]interface{}) (*Resour
 		}
 	}
 
+	if included, ok := m["@included"].([]interface{}); ok {
+		for _, v := range included {
+			vm, ok := v.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			r, err := d.parseResource(vm)
+			if err != nil {
+				return n, err
+			}
+			n.Included = append(n.Included, r)
+		}
+		linkIncluded(n)
+	}
+
 	return n, nil
 }
 
-func (d *Decoder) parseContext(ctx *Context, v interface{}) (*Context, error) {
-	var err error
-	switch v := v.(type) {
-	case []interface{}:
-		for _, vv := range v {
-			ctx, err = d.parseContext(ctx, vv)
-			if err != nil {
-				return nil, err
+// linkIncluded replaces the node references reachable from r with the
+// included nodes which have the same identifier.
+func linkIncluded(r *Resource) {
+	nodes := make(map[string]*Resource)
+	var collect func(included []*Resource)
+	collect = func(included []*Resource) {
+		for _, n := range included {
+			if n.ID != "" {
+				nodes[n.ID] = n
+			}
+			collect(n.Included)
+		}
+	}
+	collect(r.Included)
+
+	visited := make(map[*Resource]bool)
+	var visit func(r *Resource)
+	var link func(values []interface{})
+	visit = func(r *Resource) {
+		if visited[r] {
+			return
+		}
+		visited[r] = true
+		for _, props := range []Props{r.Props, r.Reverse} {
+			for _, values := range props {
+				link(values)
+			}
+		}
+		for _, n := range r.Included {
+			visit(n)
+		}
+	}
+	link = func(values []interface{}) {
+		for i, v := range values {
+			switch v := v.(type) {
+			case *Resource:
+				isRef := v.Props == nil && v.Reverse == nil && v.Included == nil
+				if n, ok := nodes[v.ID]; ok && isRef {
+					values[i] = n
+				} else {
+					visit(v)
+				}
+			case List:
+				link(v)
+			case Indexed:
+				item := []interface{}{v.Value}
+				link(item)
+				v.Value = item[0]
+				values[i] = v
 			}
 		}
-	case map[string]interface{}:
-		ctx, err = d.parseContextMap(ctx, v)
-	case string:
-		ctx, err = d.fetchContext(ctx, v)
-	default:
-		err = errors.New("jsonld: malformed context")
 	}
-	return ctx, err
+	visit(r)
 }
 
-func (d *Decoder) parseContextMap(ctx *Context, m map[string]interface{}) (*Context, error) {
-	child := ctx.newChild(nil)
-
-	if lang, ok := m["@lang"].(string); ok {
-		child.Lang = lang
+// unmarshalGraph stores each node of a graph in an element of a slice.
+func (d *Decoder) unmarshalGraph(p *processor, ctx *Context, g Graph, dst reflect.Value) error {
+	s := reflect.MakeSlice(dst.Type(), len(g), len(g))
+	for i, r := range g {
+		elem := s.Index(i)
+		if elem.Type() == reflect.TypeOf(r) {
+			elem.Set(reflect.ValueOf(r))
+			continue
+		}
+		if elem.Kind() == reflect.Ptr {
+			elem.Set(reflect.New(elem.Type().Elem()))
+			elem = elem.Elem()
+		}
+		if err := d.unmarshalResource(p, ctx, r, elem); err != nil {
+			return err
+		}
 	}
-	if base, ok := m["@base"].(string); ok {
-		child.Base = base
+	dst.Set(s)
+	return nil
+}
+
+func (d *Decoder) unmarshal(p *processor, ctx *Context, src interface{}, dst reflect.Value) error {
+	if l, ok := src.(List); ok && isMultiValued(dst.Type()) {
+		return d.unmarshalValues(p, ctx, l, dst)
 	}
-	if vocab, ok := m["@vocab"].(string); ok {
-		child.Vocab = vocab
+	if v, ok := src.(Indexed); ok && dst.Type() != reflect.TypeOf(v) && dst.Kind() != reflect.Interface {
+		// The index is dropped when not decoding into a map
+		return d.unmarshal(p, ctx, v.Value, dst)
 	}
 
-	for k, v := range m {
-		if len(k) > 0 && k[0] == '@' {
-			continue
+	switch src := src.(type) {
+	case *Resource:
+		if dst.Kind() != reflect.Interface {
+			return d.unmarshalResource(p, ctx, src, dst)
 		}
+	case LangString:
+		// The language and direction are dropped when decoding into a
+		// string
+		if dst.Kind() == reflect.String {
+			dst.SetString(src.Value)
+			return nil
+		}
+	case string:
+		if dst.Type() == reflect.TypeOf(LangString{}) {
+			dst.Set(reflect.ValueOf(LangString{Value: src}))
+			return nil
+		}
+	}
 
-		v, err := d.parse(nil, v, "")
-		if err != nil {
-			return nil, err
+	rsrc := reflect.ValueOf(src)
+	if !rsrc.Type().AssignableTo(dst.Type()) {
+		return fmt.Errorf("jsonld: cannot unmarshal %v to %v", rsrc.Type(), dst.Type())
+	}
+	dst.Set(rsrc)
+	return nil
+}
+
+// unmarshalValue stores a value in dst, allocating a pointer if necessary.
+func (d *Decoder) unmarshalValue(p *processor, ctx *Context, src interface{}, dst reflect.Value) error {
+	if dst.Kind() == reflect.Ptr {
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
 		}
+		dst = reflect.Indirect(dst)
+	}
+	return d.unmarshal(p, ctx, src, dst)
+}
 
-		var term *Resource
-		switch v := v.(type) {
-		case *Resource:
-			term = v
-		case string:
-			term = &Resource{ID: v}
-		default:
-			if v != nil {
-				return nil, errors.New("jsonld: malformed context value")
+// unmarshalValues stores values in a slice or an array.
+func (d *Decoder) unmarshalValues(p *processor, ctx *Context, values []interface{}, dst reflect.Value) error {
+	if dst.Kind() == reflect.Array {
+		if len(values) > dst.Len() {
+			return fmt.Errorf("jsonld: cannot unmarshal %v values to %v", len(values), dst.Type())
+		}
+		for i, v := range values {
+			if err := d.unmarshalValue(p, ctx, v, dst.Index(i)); err != nil {
+				return err
 			}
 		}
-		child.Terms[k] = term
+		return nil
 	}
 
-	return child, nil
+	s := reflect.MakeSlice(dst.Type(), len(values), len(values))
+	for i, v := range values {
+		if err := d.unmarshalValue(p, ctx, v, s.Index(i)); err != nil {
+			return err
+		}
+	}
+	dst.Set(s)
+	return nil
 }
 
-func (d *Decoder) fetchContext(ctx *Context, url string) (*Context, error) {
-	// TODO: inherit from ctx
-	if d.FetchContext != nil {
-		return d.FetchContext(url)
+// unmarshalMap stores values in a map. Values are keyed by language, index,
+// node identifier or type, depending on container. Values without a key are
+// stored with the "@none" key.
+func (d *Decoder) unmarshalMap(p *processor, ctx *Context, values []interface{}, container string, dst reflect.Value) error {
+	t := dst.Type()
+	if t.Key().Kind() != reflect.String {
+		return fmt.Errorf("jsonld: cannot unmarshal to %v", t)
+	}
+	// Multiple values with the same key can be stored in slices
+	multi := t.Elem().Kind() == reflect.Slice && isMultiValued(t.Elem())
+
+	m := reflect.MakeMap(t)
+	for _, v := range values {
+		k := mapKey(v, container)
+		if k == "" {
+			k = "@none"
+		}
+		key := reflect.ValueOf(k).Convert(t.Key())
+
+		elem := reflect.New(t.Elem()).Elem()
+		if multi {
+			item := reflect.New(t.Elem().Elem()).Elem()
+			if err := d.unmarshalValue(p, ctx, v, item); err != nil {
+				return err
+			}
+			if old := m.MapIndex(key); old.IsValid() {
+				elem.Set(old)
+			}
+			elem = reflect.Append(elem, item)
+		} else if err := d.unmarshalValue(p, ctx, v, elem); err != nil {
+			return err
+		}
+		m.SetMapIndex(key, elem)
 	}
-	return nil, errors.New("jsonld: fetching remote contexts is disabled")
+	dst.Set(m)
+	return nil
 }
 
-func (d *Decoder) unmarshal(src interface{}, dst reflect.Value) error {
-	switch src := src.(type) {
+// mapKey returns the key of a value in a map with the specified container.
+func mapKey(v interface{}, container string) string {
+	if ix, ok := v.(Indexed); ok {
+		if container == "@index" {
+			return ix.Index
+		}
+		v = ix.Value
+	}
+
+	switch v := v.(type) {
+	case LangString:
+		if container == "@language" {
+			return v.Language
+		}
 	case *Resource:
-		return d.unmarshalResource(src, dst)
-	default:
-		rsrc := reflect.ValueOf(src)
-		if dst.Type() == rsrc.Type() {
-			dst.Set(rsrc)
-			return nil
-		} else {
-			return fmt.Errorf("jsonld: cannot unmarshal %v to %v", rsrc.Type(), dst.Type())
+		switch container {
+		case "@id":
+			return v.ID
+		case "@type":
+			return v.Props.Type()
 		}
 	}
+	return ""
 }
 
-func (d *Decoder) unmarshalResource(r *Resource, v reflect.Value) error {
+func (d *Decoder) unmarshalResource(p *processor, ctx *Context, r *Resource, v reflect.Value) error {
 	// TODO: do not panic
 
 	t := v.Type()
@@ -310,31 +669,73 @@ func (d *Decoder) unmarshalResource(r *Resource, v reflect.Value) error {
 			}
 			f.Set(reflect.ValueOf(Type{typeURI}))
 		} else {
-			k, ok := getFieldURI(d.Context, ft)
+			field, ok := getField(ctx, ft)
 			if !ok {
 				continue
 			}
 
-			if k == "@id" {
+			if field.uri == "@id" {
 				f.SetString(r.ID)
-			} else {
-				fv := r.Props.Get(k)
-				if fv == nil {
+				continue
+			}
+			if field.uri == "@included" {
+				if len(r.Included) == 0 {
 					continue
 				}
+				values := make([]interface{}, len(r.Included))
+				for i, n := range r.Included {
+					values[i] = n
+				}
+				if err := d.unmarshalValues(p, ctx, values, f); err != nil {
+					return err
+				}
+				continue
+			}
 
-				if f.Kind() == reflect.Ptr {
-					if f.IsNil() {
-						f.Set(reflect.New(f.Type().Elem()))
-					}
-					f = reflect.Indirect(f)
+			if field.uri == "@type" {
+				// Types are stored as IRIs, like in the JSONLDType field
+				values := r.Props[propType]
+				if len(values) == 0 {
+					continue
 				}
-				if err := d.unmarshal(fv, f); err != nil {
+				if err := d.unmarshalField(p, ctx, values, f, field); err != nil {
 					return err
 				}
+				continue
+			}
+
+			props := r.Props
+			if field.reverse {
+				props = r.Reverse
+			}
+			values := props[field.uri]
+			if len(values) == 0 {
+				continue
+			}
+
+			valueCtx, err := p.valueContext(ctx, field)
+			if err != nil {
+				return err
+			}
+			if err := d.unmarshalField(p, valueCtx, values, f, field); err != nil {
+				return err
 			}
 		}
 	}
 
 	return nil
 }
+
+// unmarshalField stores the values of a property in a struct field.
+func (d *Decoder) unmarshalField(p *processor, ctx *Context, values []interface{}, f reflect.Value, field field) error {
+	if f.Kind() == reflect.Map {
+		return d.unmarshalMap(p, ctx, values, field.container, f)
+	} else if isMultiValued(f.Type()) {
+		// If the property contains a single list, store its items
+		if l, ok := values[0].(List); ok && len(values) == 1 {
+			values = l
+		}
+		return d.unmarshalValues(p, ctx, values, f)
+	}
+	return d.unmarshalValue(p, ctx, values[0], f)
+}
@@ -2,16 +2,39 @@ package jsonld
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
 	"reflect"
+	"sort"
 )
 
 // Encoder encodes JSON-LD values.
 type Encoder struct {
 	// If specified, this context will be used when encoding values.
 	Context *Context
+	// FetchContext, if non-nil, will be called to fetch remote contexts, such
+	// as scoped contexts referenced by the terms of Context. By default,
+	// remote contexts are not fetched.
+	FetchContext FetchContextFunc
+	// FetchDocument, if non-nil, will be called to fetch the contexts
+	// referenced by @import in scoped contexts. By default, they are not
+	// fetched.
+	FetchDocument FetchDocumentFunc
+	// ProcessingMode is either "json-ld-1.1" (the default) or "json-ld-1.0".
+	// In JSON-LD 1.0 mode, values which can't be represented without
+	// JSON-LD 1.1 features result in an error.
+	ProcessingMode string
+	// KeepAbsoluteIRIs, if set, prevents IRIs from being converted to
+	// relative IRI references against the base IRI of Context.
+	KeepAbsoluteIRIs bool
 
 	enc *json.Encoder
+
+	// State for the current Encode call
+	issuer *IdentifierIssuer
+	refs map[*Resource]int
+	labels map[*Resource]string
+	visited map[*Resource]bool
 }
 
 // NewEncoder creates a new JSON-LD encoder.
@@ -20,91 +43,357 @@ func NewEncoder(w io.Writer) *Encoder {
 }
 
 // Encode encodes a JSON-LD value.
+//
+// Datasets, graphs and slices of resources are encoded with a @graph entry
+// when they contain more than one node.
 func (e *Encoder) Encode(v interface{}) error {
-	raw, err := e.marshal(reflect.ValueOf(v))
-	if err != nil {
+	p := newProcessor(&Options{
+		FetchContext: e.FetchContext,
+		FetchDocument: e.FetchDocument,
+		KeepAbsoluteIRIs: e.KeepAbsoluteIRIs,
+		ProcessingMode: e.ProcessingMode,
+	})
+	if err := p.checkProcessingMode(e.Context); err != nil {
 		return err
 	}
 
-	raw, err = e.format(raw)
+	var ds *Dataset
+	switch v := v.(type) {
+	case *Dataset:
+		ds = v
+	case Dataset:
+		ds = &v
+	default:
+		raw, err := e.marshal(p, e.Context, reflect.ValueOf(v))
+		if err != nil {
+			return err
+		}
+		if r, ok := raw.(*Resource); ok {
+			ds = &Dataset{Default: Graph{r}}
+		} else if g, ok, err := e.marshalGraph(p, e.Context, reflect.ValueOf(v)); err != nil {
+			return err
+		} else if ok {
+			ds = &Dataset{Default: g}
+		} else {
+			if e.Context != nil {
+				raw = map[string]interface{}{
+					"@context": formatContext(e.Context),
+					"@value": raw,
+				}
+			}
+			return e.enc.Encode(raw)
+		}
+	}
+
+	expanded := e.formatDataset(ds)
+	if p.isJSONLD10() {
+		if err := checkJSONLD10(expanded); err != nil {
+			return err
+		}
+	}
+	compacted, err := p.compactDocument(expanded, e.Context)
 	if err != nil {
 		return err
 	}
+	return e.enc.Encode(compacted)
+}
 
-	if e.Context != nil {
-		formattedCtx, err := e.formatContext(e.Context)
+// checkJSONLD10 checks that an expanded value doesn't use JSON-LD 1.1
+// features: included blocks, base directions, JSON literals and lists of
+// lists.
+func checkJSONLD10(v interface{}) error {
+	switch v := v.(type) {
+	case []interface{}:
+		for _, item := range v {
+			if err := checkJSONLD10(item); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		for _, kw := range []string{"@included", "@direction"} {
+			if _, ok := v[kw]; ok {
+				return fmt.Errorf("jsonld: %v requires JSON-LD 1.1", kw)
+			}
+		}
+		if _, ok := v["@value"]; ok {
+			if v["@type"] == "@json" {
+				return fmt.Errorf("jsonld: JSON literals require JSON-LD 1.1")
+			}
+			return nil
+		}
+		if l, ok := v["@list"].([]interface{}); ok {
+			for _, item := range l {
+				if _, ok := item.(map[string]interface{})["@list"]; ok {
+					return fmt.Errorf("jsonld: lists of lists require JSON-LD 1.1")
+				}
+			}
+		}
+		for _, item := range v {
+			if err := checkJSONLD10(item); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// marshalGraph converts a slice of resources or structs to a graph. It returns
+// false if v isn't such a slice.
+func (e *Encoder) marshalGraph(p *processor, ctx *Context, v reflect.Value) (Graph, bool, error) {
+	v = reflect.Indirect(v)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, false, nil
+	}
+
+	g := make(Graph, 0, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		raw, err := e.marshal(p, ctx, v.Index(i))
 		if err != nil {
-			return err
+			return nil, false, err
 		}
-		if m, ok := raw.(map[string]interface{}); ok {
-			m["@context"] = formattedCtx
-		} else {
-			raw = map[string]interface{}{
-				"@context": formattedCtx,
-				"@value": raw,
+		r, ok := raw.(*Resource)
+		if !ok {
+			return nil, false, nil
+		}
+		g = append(g, r)
+	}
+	return g, true, nil
+}
+
+// formatDataset converts a dataset to an expanded document. Named graphs are
+// converted to nodes with a @graph entry.
+func (e *Encoder) formatDataset(ds *Dataset) []interface{} {
+	e.issuer = NewIdentifierIssuer("_:b")
+	e.refs = make(map[*Resource]int)
+	e.labels = make(map[*Resource]string)
+
+	graphs := []Graph{ds.Default}
+	for _, g := range ds.Named {
+		graphs = append(graphs, g)
+	}
+	for _, g := range graphs {
+		for _, r := range g {
+			e.countRefs(r)
+		}
+	}
+
+	expanded := e.formatGraph(ds.Default)
+
+	names := make([]string, 0, len(ds.Named))
+	for name := range ds.Named {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		id := name
+		if isBlankNodeID(id) {
+			id = e.issuer.Issue(id)
+		}
+
+		var node map[string]interface{}
+		for _, v := range expanded {
+			if m := v.(map[string]interface{}); m["@id"] == id {
+				node = m
+				break
 			}
 		}
+		if node == nil {
+			node = map[string]interface{}{"@id": id}
+			expanded = append(expanded, node)
+		}
+
+		node["@graph"] = e.formatGraph(ds.Named[name])
 	}
 
-	return e.enc.Encode(raw)
+	return expanded
 }
 
-func (e *Encoder) format(v interface{}) (interface{}, error) {
+func (e *Encoder) formatGraph(g Graph) []interface{} {
+	// Nodes are embedded once per graph
+	e.visited = make(map[*Resource]bool)
+
+	nodes := make([]interface{}, 0, len(g))
+	for _, r := range g {
+		nodes = append(nodes, e.formatResource(r))
+	}
+	return nodes
+}
+
+// formatValue converts a Go value to an expanded JSON-LD value.
+func (e *Encoder) formatValue(v interface{}) interface{} {
 	switch v := v.(type) {
+	case nil:
+		return nil
 	case *Resource:
 		return e.formatResource(v)
+	case List:
+		items := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			if item := e.formatValue(item); item != nil {
+				items = append(items, item)
+			}
+		}
+		return map[string]interface{}{"@list": items}
+	case Indexed:
+		formatted := e.formatValue(v.Value)
+		if m, ok := formatted.(map[string]interface{}); ok {
+			m["@index"] = v.Index
+		}
+		return formatted
+	case json.RawMessage:
+		var raw interface{}
+		if err := json.Unmarshal(v, &raw); err != nil {
+			return nil
+		}
+		return map[string]interface{}{"@value": raw, "@type": "@json"}
+	case LangString:
+		m := map[string]interface{}{"@value": v.Value}
+		if v.Language != "" {
+			m["@language"] = v.Language
+		}
+		if v.Direction != "" {
+			m["@direction"] = v.Direction
+		}
+		return m
 	default:
-		return v, nil
+		return map[string]interface{}{"@value": v}
+	}
+}
+
+// countRefs counts the references to each resource reachable from r.
+func (e *Encoder) countRefs(r *Resource) {
+	e.refs[r]++
+	if e.refs[r] > 1 {
+		return
+	}
+	for _, props := range []Props{r.Props, r.Reverse} {
+		for _, values := range props {
+			e.countValueRefs(values)
+		}
+	}
+	for _, n := range r.Included {
+		e.countRefs(n)
+	}
+}
+
+func (e *Encoder) countValueRefs(values []interface{}) {
+	for _, v := range values {
+		switch v := v.(type) {
+		case *Resource:
+			e.countRefs(v)
+		case List:
+			e.countValueRefs(v)
+		case Indexed:
+			e.countValueRefs([]interface{}{v.Value})
+		}
+	}
+}
+
+// resourceID returns the node identifier of a resource. Blank node
+// identifiers are relabeled, and resources without an identifier are labeled
+// if they are referenced more than once.
+func (e *Encoder) resourceID(r *Resource) string {
+	if id, ok := e.labels[r]; ok {
+		return id
+	}
+
+	id := r.ID
+	if id == "" && e.refs[r] > 1 {
+		id = e.issuer.Issue("")
+	} else if isBlankNodeID(id) {
+		id = e.issuer.Issue(id)
 	}
+	e.labels[r] = id
+	return id
 }
 
-func (e *Encoder) formatResource(r *Resource) (map[string]interface{}, error) {
+// formatResource converts a resource to an expanded node object. Resources
+// which have already been formatted are converted to node references.
+func (e *Encoder) formatResource(r *Resource) map[string]interface{} {
 	m := make(map[string]interface{})
-	ctx := e.Context
 
-	if r.ID != "" {
-		// TODO: use ctx.Base to produce relative URIs when possible
-		m["@id"] = r.ID
+	id := e.resourceID(r)
+	if id != "" {
+		m["@id"] = id
+		if e.visited[r] {
+			return m
+		}
+	}
+	e.visited[r] = true
+
+	// Included nodes are formatted first, so that they are embedded in the
+	// @included entry rather than in property values
+	if len(r.Included) > 0 {
+		included := make([]interface{}, 0, len(r.Included))
+		for _, n := range r.Included {
+			included = append(included, e.formatResource(n))
+		}
+		m["@included"] = included
 	}
 
-	for k, values := range r.Props {
+	// Iterate in a stable order, so that blank node labels are deterministic
+	for _, k := range sortedPropKeys(r.Props) {
+		values := r.Props[k]
 		if k == propType {
-			k = "@type"
-
-			for i, v := range values {
+			types := make([]interface{}, 0, len(values))
+			for _, v := range values {
 				if s, ok := v.(string); ok {
-					values[i], _ = ctx.reduce(s)
+					types = append(types, s)
 				}
 			}
+			m["@type"] = types
+			continue
 		}
 
-		k, term := ctx.reduce(k)
-		if term != nil && term.Props.hasType("@id") {
-			for i, v := range values {
-				if r, ok := v.(*Resource); ok && len(r.Props) == 0 {
-					values[i] = r.ID
-				}
+		var expanded []interface{}
+		for _, v := range values {
+			if v := e.formatValue(v); v != nil {
+				expanded = append(expanded, v)
 			}
 		}
+		if len(expanded) > 0 {
+			m[k] = expanded
+		}
+	}
 
-		var err error
-		if len(values) == 1 {
-			m[k], err = e.format(values[0])
-		} else {
-			m[k], err = e.format(values)
+	if len(r.Reverse) > 0 {
+		reverse := make(map[string]interface{})
+		for _, k := range sortedPropKeys(r.Reverse) {
+			var expanded []interface{}
+			for _, v := range r.Reverse[k] {
+				if child, ok := v.(*Resource); ok {
+					expanded = append(expanded, e.formatResource(child))
+				}
+			}
+			if len(expanded) > 0 {
+				reverse[k] = expanded
+			}
 		}
-		if err != nil {
-			return m, err
+		if len(reverse) > 0 {
+			m["@reverse"] = reverse
 		}
 	}
 
-	return m, nil
+	return m
+}
+
+func sortedPropKeys(props Props) []string {
+	keys := make([]string, 0, len(props))
+	for k := range props {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
 }
 
-func (e *Encoder) marshal(v reflect.Value) (interface{}, error) {
+func (e *Encoder) marshal(p *processor, ctx *Context, v reflect.Value) (interface{}, error) {
 	switch v.Kind() {
 	case reflect.Struct:
-		r, err := e.marshalResource(v)
+		if t := v.Type(); t == reflect.TypeOf(LangString{}) || t == reflect.TypeOf(Indexed{}) {
+			return v.Interface(), nil
+		}
+		r, err := e.marshalResource(p, ctx, v)
 		if err != nil {
 			return nil, err
 		}
@@ -113,13 +402,28 @@ func (e *Encoder) marshal(v reflect.Value) (interface{}, error) {
 		if v.IsNil() {
 			return nil, nil
 		}
-		return e.marshal(reflect.Indirect(v))
+		if r, ok := v.Interface().(*Resource); ok {
+			return r, nil
+		}
+		return e.marshal(p, ctx, reflect.Indirect(v))
+	case reflect.Interface:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return e.marshal(p, ctx, v.Elem())
 	default:
+		if raw, ok := v.Interface().(json.RawMessage); ok {
+			if len(raw) == 0 {
+				return nil, nil
+			} else if !json.Valid(raw) {
+				return nil, fmt.Errorf("jsonld: invalid JSON literal")
+			}
+		}
 		return v.Interface(), nil
 	}
 }
 
-func (e *Encoder) marshalResource(v reflect.Value) (*Resource, error) {
+func (e *Encoder) marshalResource(p *processor, ctx *Context, v reflect.Value) (*Resource, error) {
 	// TODO: don't panic
 
 	// TODO: use &Resource instead
@@ -137,26 +441,73 @@ func (e *Encoder) marshalResource(v reflect.Value) (*Resource, error) {
 		ft := t.Field(i)
 
 		if typeURI, ok := typeField(ft); ok {
+			if r.Props == nil {
+				r.Props = make(Props)
+			}
 			r.Props.Set(propType, typeURI)
 		} else {
-			k, ok := getFieldURI(e.Context, ft)
+			field, ok := getField(ctx, ft)
 			if !ok {
 				continue
 			}
 
-			if k == "@id" {
+			if field.uri == "@id" {
 				r.ID = f.String()
-			} else {
-				raw, err := e.marshal(f)
-				if err != nil {
-					return r, err
+				continue
+			}
+
+			valueCtx, err := p.valueContext(ctx, field)
+			if err != nil {
+				return r, err
+			}
+			values, err := e.marshalField(p, valueCtx, f, field)
+			if err != nil {
+				return r, err
+			}
+			if len(values) == 0 {
+				continue
+			}
+
+			if field.uri == "@type" {
+				for _, v := range values {
+					t, ok := v.(string)
+					if !ok {
+						return r, fmt.Errorf("jsonld: types must be strings")
+					}
+					if r.Props == nil {
+						r.Props = make(Props)
+					}
+					r.Props.Add(propType, ctx.expand(t))
 				}
+				continue
+			}
 
+			if field.uri == "@included" {
+				for _, v := range values {
+					n, ok := v.(*Resource)
+					if !ok {
+						return r, fmt.Errorf("jsonld: included values must be resources")
+					}
+					r.Included = append(r.Included, n)
+				}
+				continue
+			}
+
+			if field.reverse {
+				for _, v := range values {
+					if _, ok := v.(*Resource); !ok {
+						return r, fmt.Errorf("jsonld: reverse property %v must be a resource", field.uri)
+					}
+				}
+				if r.Reverse == nil {
+					r.Reverse = make(Props)
+				}
+				r.Reverse[field.uri] = append(r.Reverse[field.uri], values...)
+			} else {
 				if r.Props == nil {
 					r.Props = make(Props)
 				}
-
-				r.Props.Add(k, raw)
+				r.Props[field.uri] = append(r.Props[field.uri], values...)
 			}
 		}
 	}
@@ -164,37 +515,120 @@ func (e *Encoder) marshalResource(v reflect.Value) (*Resource, error) {
 	return r, nil
 }
 
-func (e *Encoder) formatContext(ctx *Context) (interface{}, error) {
-	if ctx == nil {
-		return nil, nil
+// marshalField converts a struct field to property values. Slices and arrays
+// are converted to multiple values, or to a single List if field.list is set.
+func (e *Encoder) marshalField(p *processor, ctx *Context, f reflect.Value, field field) ([]interface{}, error) {
+	if f.Kind() == reflect.Map {
+		return e.marshalMap(p, ctx, f, field.container)
 	}
-	if ctx.URL != "" {
-		return ctx.URL, nil
+	if !isMultiValued(f.Type()) {
+		raw, err := e.marshal(p, ctx, f)
+		if err != nil {
+			return nil, err
+		}
+		if field.reverse && raw == nil {
+			return nil, nil
+		}
+		return []interface{}{raw}, nil
 	}
 
-	m := make(map[string]interface{})
+	if f.Kind() == reflect.Slice && f.IsNil() {
+		return nil, nil
+	}
 
-	if ctx.Lang != "" {
-		m["@lang"] = ctx.Lang
+	values := make([]interface{}, 0, f.Len())
+	for i := 0; i < f.Len(); i++ {
+		raw, err := e.marshal(p, ctx, f.Index(i))
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, raw)
 	}
-	if ctx.Base != "" {
-		m["@base"] = ctx.Base
+	if field.list {
+		return []interface{}{List(values)}, nil
 	}
-	if ctx.Vocab != "" {
-		m["@vocab"] = ctx.Vocab
+	return values, nil
+}
+
+// marshalMap converts a map to property values. Map keys are stored in the
+// values as languages, indexes, node identifiers or types, depending on
+// container. Values with the "@none" key are stored as-is.
+func (e *Encoder) marshalMap(p *processor, ctx *Context, m reflect.Value, container string) ([]interface{}, error) {
+	if m.Type().Key().Kind() != reflect.String {
+		return nil, fmt.Errorf("jsonld: cannot marshal %v", m.Type())
 	}
 
-	for k, term := range ctx.Terms {
-		if len(term.Props) == 0 {
-			m[k] = term.ID
-		} else {
-			raw, err := e.formatResource(term)
+	keys := m.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].String() < keys[j].String()
+	})
+
+	var values []interface{}
+	for _, key := range keys {
+		elem := m.MapIndex(key)
+		items := []reflect.Value{elem}
+		if elem.Kind() == reflect.Slice && isMultiValued(elem.Type()) {
+			items = items[:0]
+			for i := 0; i < elem.Len(); i++ {
+				items = append(items, elem.Index(i))
+			}
+		}
+
+		for _, item := range items {
+			raw, err := e.marshal(p, ctx, item)
 			if err != nil {
-				return m, err
+				return nil, err
 			}
-			m[k] = raw
+			if raw == nil {
+				continue
+			}
+			if key.String() != "@none" {
+				if raw, err = e.addMapKey(p, ctx, raw, key.String(), container); err != nil {
+					return nil, err
+				}
+			}
+			values = append(values, raw)
 		}
 	}
+	return values, nil
+}
 
-	return m, nil
+// addMapKey stores a map key in a value.
+func (e *Encoder) addMapKey(p *processor, ctx *Context, v interface{}, key, container string) (interface{}, error) {
+	switch container {
+	case "@language":
+		switch v := v.(type) {
+		case string:
+			return LangString{Value: v, Language: key}, nil
+		case LangString:
+			if v.Language == "" {
+				v.Language = key
+			}
+			return v, nil
+		}
+		return nil, fmt.Errorf("jsonld: language map values must be strings")
+	case "@id", "@type":
+		r, ok := v.(*Resource)
+		if !ok {
+			return nil, fmt.Errorf("jsonld: %v map values must be resources", container)
+		}
+		if container == "@id" {
+			if r.ID == "" {
+				copy := *r
+				copy.ID = key
+				r = &copy
+			}
+		} else if t := ctx.expand(key); !r.Props.hasType(t) {
+			copy := *r
+			copy.Props = make(Props, len(r.Props)+1)
+			for k, values := range r.Props {
+				copy.Props[k] = values
+			}
+			copy.Props[propType] = append([]interface{}{t}, r.Props[propType]...)
+			r = &copy
+		}
+		return r, nil
+	default:
+		return Indexed{Index: key, Value: v}, nil
+	}
 }
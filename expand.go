@@ -0,0 +1,655 @@
+package jsonld
+
+import (
+	"sort"
+	"strings"
+)
+
+// Expand expands a JSON-LD document, as defined in
+// https://www.w3.org/TR/json-ld11-api/#expansion-algorithm.
+//
+// The input document must be a value decoded by the encoding/json package.
+// The result is a list of node objects in expanded form: contexts are removed,
+// all IRIs are absolute and all values are value objects, node objects or list
+// objects.
+func Expand(input interface{}, opts *Options) ([]interface{}, error) {
+	return newProcessor(opts).expandDocument(input)
+}
+
+func (p *processor) expandDocument(input interface{}) ([]interface{}, error) {
+	if err := p.checkProcessingMode(p.opts.ExpandContext); err != nil {
+		return nil, err
+	}
+	ctx := (&Context{Base: p.opts.Base}).newChild(p.opts.ExpandContext)
+
+	v, err := p.expand(ctx, "", input)
+	if err != nil {
+		return nil, err
+	}
+
+	if m, ok := v.(map[string]interface{}); ok && len(m) == 1 {
+		if g, ok := m["@graph"]; ok {
+			v = g
+		}
+	}
+	if v == nil {
+		return []interface{}{}, nil
+	}
+	return toArray(v), nil
+}
+
+// toArray wraps v in an array if it isn't one already.
+func toArray(v interface{}) []interface{} {
+	switch v := v.(type) {
+	case []interface{}:
+		if v == nil {
+			return []interface{}{}
+		}
+		return v
+	case nil:
+		return []interface{}{}
+	default:
+		return []interface{}{v}
+	}
+}
+
+// addValue adds v to the values of the entry k of m. If asArray is set, the
+// entry is always an array. Otherwise, the entry only becomes an array when it
+// contains more than one value.
+func addValue(m map[string]interface{}, k string, v interface{}, asArray bool) {
+	old, exists := m[k]
+	if asArray {
+		m[k] = toArray(old)
+	}
+
+	if a, ok := v.([]interface{}); ok {
+		for _, item := range a {
+			addValue(m, k, item, asArray)
+		}
+	} else if !exists && !asArray {
+		m[k] = v
+	} else {
+		m[k] = append(toArray(m[k]), v)
+	}
+}
+
+// expand expands an element. prop is the active property, or an empty string
+// if there is none.
+func (p *processor) expand(ctx *Context, prop string, element interface{}) (interface{}, error) {
+	switch element := element.(type) {
+	case nil:
+		return nil, nil
+	case []interface{}:
+		return p.expandArray(ctx, prop, element)
+	case map[string]interface{}:
+		return p.expandMap(ctx, prop, element)
+	default:
+		// Drop free-floating scalars
+		if prop == "" || prop == "@graph" {
+			return nil, nil
+		}
+		// Scalars use the scoped context of their property
+		scoped, err := p.applyScopedContext(ctx, ctx.Terms[prop], propertyScope)
+		if err != nil {
+			return nil, err
+		}
+		return p.expandValue(scoped, prop, element), nil
+	}
+}
+
+func (p *processor) expandArray(ctx *Context, prop string, a []interface{}) ([]interface{}, error) {
+	isList := termHasContainer(ctx.Terms[prop], "@list")
+
+	result := []interface{}{}
+	for _, item := range a {
+		v, err := p.expand(ctx, prop, item)
+		if err != nil {
+			return nil, err
+		}
+		if l, ok := v.([]interface{}); ok && isList {
+			v = map[string]interface{}{"@list": l}
+		}
+
+		switch v := v.(type) {
+		case nil:
+			// Skip
+		case []interface{}:
+			result = append(result, v...)
+		default:
+			result = append(result, v)
+		}
+	}
+	return result, nil
+}
+
+func (p *processor) expandMap(ctx *Context, prop string, m map[string]interface{}) (interface{}, error) {
+	var err error
+	term := ctx.Terms[prop]
+
+	// Contexts which aren't propagated don't apply to nested node objects
+	if ctx.previous != nil && !isValueOrReference(ctx, m) {
+		ctx = ctx.previous
+	}
+	if ctx, err = p.applyScopedContext(ctx, term, propertyScope); err != nil {
+		return nil, err
+	}
+	if v, ok := m["@context"]; ok {
+		if ctx, err = p.parseContext(ctx, v); err != nil {
+			return nil, err
+		}
+	}
+
+	// Types are expanded with the context in effect before type-scoped
+	// contexts are applied
+	typeCtx := ctx
+	if ctx, err = p.applyTypeScopedContexts(ctx, m); err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]interface{})
+	if err := p.expandObject(ctx, typeCtx, prop, m, result); err != nil {
+		return nil, err
+	}
+
+	if v, ok := result["@value"]; ok && !p.frameExpansion {
+		for k := range result {
+			switch k {
+			case "@value", "@language", "@direction", "@type", "@index":
+			default:
+				return nil, errorf("invalid value object", "unexpected %q", k)
+			}
+		}
+
+		_, hasLang := result["@language"]
+		_, hasDir := result["@direction"]
+		_, hasType := result["@type"]
+		if (hasLang || hasDir) && hasType {
+			return nil, errorf("invalid value object", "both @type and @language or @direction are set")
+		}
+		if result["@type"] != "@json" {
+			switch v.(type) {
+			case nil:
+				return nil, nil
+			case map[string]interface{}, []interface{}:
+				return nil, errorf("invalid value object value", "")
+			}
+			if _, ok := v.(string); !ok && (hasLang || hasDir) {
+				return nil, errorf("invalid language-tagged value", "")
+			}
+			if hasType {
+				t, ok := result["@type"].(string)
+				if !ok || !IsAbsoluteIRI(t) {
+					return nil, errorf("invalid typed value", "")
+				}
+			}
+		}
+
+		// Drop free-floating values
+		if prop == "" || prop == "@graph" {
+			return nil, nil
+		}
+		return result, nil
+	}
+
+	if t, ok := result["@type"]; ok {
+		result["@type"] = toArray(t)
+	}
+
+	_, hasSet := result["@set"]
+	_, hasList := result["@list"]
+	if hasSet || hasList {
+		_, hasIndex := result["@index"]
+		if len(result) > 2 || (len(result) == 2 && !hasIndex) {
+			return nil, errorf("invalid set or list object", "")
+		}
+		if hasSet {
+			return result["@set"], nil
+		}
+	}
+
+	if _, ok := result["@language"]; ok && len(result) == 1 {
+		return nil, nil
+	}
+
+	// Drop free-floating values
+	if prop == "" || prop == "@graph" {
+		_, hasID := result["@id"]
+		if len(result) == 0 || hasList {
+			return nil, nil
+		} else if len(result) == 1 && hasID && !p.keepUnmapped && !p.frameExpansion {
+			return nil, nil
+		}
+	}
+
+	return result, nil
+}
+
+// isValueOrReference checks whether a map is a value object or a node
+// reference, before expansion.
+func isValueOrReference(ctx *Context, m map[string]interface{}) bool {
+	for k := range m {
+		switch expandIRI(ctx, k, false, true) {
+		case "@value":
+			return true
+		case "@id":
+			if len(m) == 1 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// applyTypeScopedContexts applies the scoped contexts of the types of a node
+// object, in lexicographical order. Type-scoped contexts aren't propagated by
+// default.
+func (p *processor) applyTypeScopedContexts(ctx *Context, m map[string]interface{}) (*Context, error) {
+	typeCtx := ctx
+	for _, k := range sortedKeys(m) {
+		if expandIRI(typeCtx, k, false, true) != "@type" {
+			continue
+		}
+
+		var types []string
+		for _, t := range toArray(m[k]) {
+			if s, ok := t.(string); ok {
+				types = append(types, s)
+			}
+		}
+		sort.Strings(types)
+
+		for _, t := range types {
+			var err error
+			if ctx, err = p.applyScopedContext(ctx, typeCtx.Terms[t], typeScope); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return ctx, nil
+}
+
+// expandObject expands the entries of m and adds them to result. typeCtx is
+// the context used to expand types.
+func (p *processor) expandObject(ctx, typeCtx *Context, prop string, m, result map[string]interface{}) error {
+	for _, k := range sortedKeys(m) {
+		v := m[k]
+		if k == "@context" {
+			continue
+		}
+
+		expandedProp := expandIRI(ctx, k, false, true)
+		if !isKeyword(expandedProp) && !IsAbsoluteIRI(expandedProp) && !isBlankNodeID(expandedProp) {
+			if !p.keepUnmapped || looksLikeKeyword(k) {
+				continue
+			}
+			expandedProp = k
+		}
+
+		if isKeyword(expandedProp) {
+			kwCtx := ctx
+			if expandedProp == "@type" {
+				kwCtx = typeCtx
+			}
+			if err := p.expandKeyword(kwCtx, prop, expandedProp, v, result); err != nil {
+				return err
+			}
+			continue
+		}
+
+		var ev interface{}
+		var err error
+		vm, isMap := v.(map[string]interface{})
+		switch container := termMapContainer(ctx.Terms[k]); {
+		case termType(ctx.Terms[k]) == "@json":
+			// JSON literals are kept verbatim
+			ev = map[string]interface{}{"@value": v, "@type": "@json"}
+		case isMap && container == "@language":
+			ev, err = p.expandLanguageMap(ctx, ctx.Terms[k], vm)
+		case isMap && container != "":
+			ev, err = p.expandIndexMap(ctx, k, container, vm)
+		default:
+			ev, err = p.expand(ctx, k, v)
+		}
+		if err != nil {
+			return err
+		}
+		if ev == nil {
+			continue
+		}
+
+		if termHasContainer(ctx.Terms[k], "@list") && !isListObject(ev) {
+			ev = map[string]interface{}{"@list": toArray(ev)}
+		}
+
+		if termIsReverse(ctx.Terms[k]) {
+			if err := addReverseValue(result, expandedProp, ev); err != nil {
+				return err
+			}
+			continue
+		}
+
+		addValue(result, expandedProp, ev, true)
+	}
+
+	return nil
+}
+
+func (p *processor) expandKeyword(ctx *Context, prop, kw string, v interface{}, result map[string]interface{}) error {
+	if _, ok := result[kw]; ok && kw != "@type" && kw != "@reverse" && kw != "@nest" && kw != "@included" {
+		return errorf("colliding keywords", "%q", kw)
+	}
+
+	if p.frameExpansion {
+		if ok, err := p.expandFrameKeyword(ctx, prop, kw, v, result); ok || err != nil {
+			return err
+		}
+	}
+
+	if p.isJSONLD10() {
+		switch kw {
+		case "@direction", "@included", "@nest":
+			// Ignored in JSON-LD 1.0
+			return nil
+		}
+	}
+
+	switch kw {
+	case "@id":
+		s, ok := v.(string)
+		if !ok {
+			return errorf("invalid @id value", "")
+		}
+		if id := expandIRI(ctx, s, true, false); id != "" {
+			result["@id"] = id
+		}
+	case "@type":
+		var types []interface{}
+		switch v := v.(type) {
+		case string:
+			types = []interface{}{v}
+		case []interface{}:
+			types = v
+		default:
+			return errorf("invalid type value", "")
+		}
+
+		expanded := make([]interface{}, 0, len(types))
+		for _, t := range types {
+			s, ok := t.(string)
+			if !ok {
+				return errorf("invalid type value", "")
+			}
+			expanded = append(expanded, expandIRI(ctx, s, true, true))
+		}
+
+		if _, ok := result["@type"]; ok {
+			addValue(result, "@type", expanded, true)
+		} else if _, ok := v.(string); ok {
+			result["@type"] = expanded[0]
+		} else {
+			result["@type"] = expanded
+		}
+	case "@graph":
+		ev, err := p.expand(ctx, "@graph", v)
+		if err != nil {
+			return err
+		}
+		result["@graph"] = toArray(ev)
+	case "@value":
+		// Objects and arrays are checked once the type is known
+		result["@value"] = v
+	case "@language":
+		s, ok := v.(string)
+		if !ok {
+			return errorf("invalid language-tagged string", "")
+		}
+		result["@language"] = strings.ToLower(s)
+	case "@direction":
+		if !isDirection(v) {
+			return errorf("invalid base direction", "%v", v)
+		}
+		result["@direction"] = v
+	case "@index":
+		s, ok := v.(string)
+		if !ok {
+			return errorf("invalid @index value", "")
+		}
+		result["@index"] = s
+	case "@list":
+		// Drop free-floating lists
+		if prop == "" || prop == "@graph" {
+			return nil
+		}
+		ev, err := p.expand(ctx, prop, v)
+		if err != nil {
+			return err
+		}
+		result["@list"] = toArray(ev)
+	case "@set":
+		ev, err := p.expand(ctx, prop, v)
+		if err != nil {
+			return err
+		}
+		result["@set"] = ev
+	case "@included":
+		// Included values aren't free-floating, they must be rejected below
+		ev, err := p.expand(ctx, "@included", v)
+		if err != nil {
+			return err
+		}
+		included := toArray(ev)
+		for _, item := range included {
+			m, ok := item.(map[string]interface{})
+			if !ok || isValueObject(m) || isListObject(m) {
+				return errorf("invalid @included value", "")
+			}
+		}
+		addValue(result, "@included", included, true)
+	case "@nest":
+		// Properties of nested objects are added to the enclosing node
+		for _, item := range toArray(v) {
+			nested, ok := item.(map[string]interface{})
+			if !ok {
+				return errorf("invalid @nest value", "")
+			}
+			for k := range nested {
+				if expandIRI(ctx, k, false, true) == "@value" {
+					return errorf("invalid @nest value", "unexpected @value")
+				}
+			}
+			if err := p.expandObject(ctx, ctx, prop, nested, result); err != nil {
+				return err
+			}
+		}
+	case "@reverse":
+		if _, ok := v.(map[string]interface{}); !ok {
+			return errorf("invalid @reverse value", "")
+		}
+		ev, err := p.expand(ctx, "@reverse", v)
+		if err != nil {
+			return err
+		}
+		reverse, _ := ev.(map[string]interface{})
+		for _, k := range sortedKeys(reverse) {
+			if k == "@reverse" {
+				// Reverse properties of reverse properties are forward
+				// properties
+				doubleReverse, _ := reverse[k].(map[string]interface{})
+				for _, prop := range sortedKeys(doubleReverse) {
+					addValue(result, prop, doubleReverse[prop], true)
+				}
+				continue
+			}
+			if isKeyword(k) {
+				return errorf("invalid reverse property map", "unexpected %q", k)
+			}
+			if err := addReverseValue(result, k, reverse[k]); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// addReverseValue adds v to the reverse property prop of the node object m.
+// Values must be node objects.
+func addReverseValue(m map[string]interface{}, prop string, v interface{}) error {
+	for _, item := range toArray(v) {
+		if isValueObject(item) || isListObject(item) {
+			return errorf("invalid reverse property value", "%q", prop)
+		}
+	}
+
+	reverse, ok := m["@reverse"].(map[string]interface{})
+	if !ok {
+		reverse = make(map[string]interface{})
+		m["@reverse"] = reverse
+	}
+	addValue(reverse, prop, v, true)
+	return nil
+}
+
+// expandLanguageMap expands a language map, as defined in step 13.7 of
+// https://www.w3.org/TR/json-ld11-api/#expansion-algorithm.
+func (p *processor) expandLanguageMap(ctx *Context, term *Resource, m map[string]interface{}) ([]interface{}, error) {
+	dir := ctx.Direction
+	if d, ok := termDirection(term); ok {
+		dir = d
+	}
+
+	result := []interface{}{}
+	for _, lang := range sortedKeys(m) {
+		for _, item := range toArray(m[lang]) {
+			if item == nil {
+				continue
+			}
+			s, ok := item.(string)
+			if !ok {
+				return nil, errorf("invalid language map value", "%q", lang)
+			}
+
+			v := map[string]interface{}{"@value": s}
+			if expandIRI(ctx, lang, false, true) != "@none" {
+				v["@language"] = strings.ToLower(lang)
+			}
+			if dir != "" {
+				v["@direction"] = dir
+			}
+			result = append(result, v)
+		}
+	}
+	return result, nil
+}
+
+// expandIndexMap expands an index map, an ID map or a type map, as defined in
+// step 13.8 of https://www.w3.org/TR/json-ld11-api/#expansion-algorithm.
+func (p *processor) expandIndexMap(ctx *Context, prop, container string, m map[string]interface{}) ([]interface{}, error) {
+	result := []interface{}{}
+	for _, k := range sortedKeys(m) {
+		index := k
+		switch {
+		case expandIRI(ctx, k, false, true) == "@none":
+			index = "@none"
+		case container == "@id":
+			index = expandIRI(ctx, k, true, false)
+		case container == "@type":
+			index = expandIRI(ctx, k, false, true)
+		}
+
+		// Values of type maps don't use type-scoped contexts of the
+		// enclosing node, but use the scoped context of their own type
+		mapCtx := ctx
+		if container == "@type" && mapCtx.previous != nil {
+			mapCtx = mapCtx.previous
+		}
+		if _, ok := termContext(ctx.Terms[k]); ok && container == "@type" {
+			var err error
+			if mapCtx, err = p.applyScopedContext(mapCtx, ctx.Terms[k], contextOptions{propagate: true}); err != nil {
+				return nil, err
+			}
+		}
+
+		ev, err := p.expand(mapCtx, prop, m[k])
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range toArray(ev) {
+			im, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if container != "@index" && isValueObject(im) {
+				return nil, errorf("invalid value object", "%q", k)
+			}
+
+			if index != "@none" {
+				switch container {
+				case "@index":
+					if _, ok := im["@index"]; !ok {
+						im["@index"] = index
+					}
+				case "@id":
+					if _, ok := im["@id"]; !ok {
+						im["@id"] = index
+					}
+				case "@type":
+					im["@type"] = append([]interface{}{index}, toArray(im["@type"])...)
+				}
+			}
+			result = append(result, im)
+		}
+	}
+	return result, nil
+}
+
+// expandValue expands a scalar value, as defined in
+// https://www.w3.org/TR/json-ld11-api/#value-expansion.
+func (p *processor) expandValue(ctx *Context, prop string, v interface{}) map[string]interface{} {
+	term := ctx.Terms[prop]
+	t := termType(term)
+
+	if s, ok := v.(string); ok {
+		switch t {
+		case "@id":
+			return map[string]interface{}{"@id": expandIRI(ctx, s, true, false)}
+		case "@vocab":
+			return map[string]interface{}{"@id": expandIRI(ctx, s, true, true)}
+		}
+	}
+
+	result := map[string]interface{}{"@value": v}
+	switch t {
+	case "", "@id", "@vocab", "@none":
+		if _, ok := v.(string); ok {
+			lang := ctx.Lang
+			if l, ok := termLanguage(term); ok {
+				lang = l
+			}
+			if lang != "" {
+				result["@language"] = lang
+			}
+
+			dir := ctx.Direction
+			if d, ok := termDirection(term); ok {
+				dir = d
+			}
+			if dir != "" {
+				result["@direction"] = dir
+			}
+		}
+	default:
+		result["@type"] = t
+	}
+	return result
+}
+
+func isListObject(v interface{}) bool {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	_, ok = m["@list"]
+	return ok
+}
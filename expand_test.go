@@ -0,0 +1,923 @@
+package jsonld
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+var expandTests = []struct{
+	name string
+	in string
+	out string
+}{
+	{
+		name: "example5",
+		in: example5,
+		out: `[{
+			"http://schema.org/name": [{"@value": "Manu Sporny"}],
+			"http://schema.org/url": [{"@id": "http://manu.sporny.org/"}],
+			"http://schema.org/image": [{"@id": "http://manu.sporny.org/images/manu.png"}]
+		}]`,
+	},
+	{
+		name: "example20",
+		in: example20,
+		out: `[{
+			"@id": "http://me.markus-lanthaler.com/",
+			"@type": ["http://xmlns.com/foaf/0.1/Person"],
+			"http://xmlns.com/foaf/0.1/name": [{"@value": "Markus Lanthaler"}],
+			"http://xmlns.com/foaf/0.1/homepage": [{"@id": "http://www.markus-lanthaler.com/"}],
+			"http://xmlns.com/foaf/0.1/depiction": [{"@id": "http://twitter.com/account/profile_image/markuslanthaler"}]
+		}]`,
+	},
+	{
+		name: "language",
+		in: `{
+			"@context": {
+				"@vocab": "http://example.org/",
+				"@language": "ja",
+				"occupation": {"@language": "EN"},
+				"age": {"@language": null}
+			},
+			"name": "花澄",
+			"occupation": "Ninja",
+			"age": "19",
+			"other": {"@value": "x", "@language": "FR"}
+		}`,
+		out: `[{
+			"http://example.org/name": [{"@value": "花澄", "@language": "ja"}],
+			"http://example.org/occupation": [{"@value": "Ninja", "@language": "en"}],
+			"http://example.org/age": [{"@value": "19"}],
+			"http://example.org/other": [{"@value": "x", "@language": "fr"}]
+		}]`,
+	},
+	{
+		name: "list",
+		in: `{
+			"@context": {
+				"ex": "http://example.org/",
+				"list": {"@id": "ex:list", "@container": "@list"},
+				"set": {"@id": "ex:set", "@container": "@set"}
+			},
+			"list": ["a", ["b"]],
+			"set": "c",
+			"ex:inline": {"@list": [{"@id": "ex:d"}]}
+		}`,
+		out: `[{
+			"http://example.org/list": [{"@list": [{"@value": "a"}, {"@list": [{"@value": "b"}]}]}],
+			"http://example.org/set": [{"@value": "c"}],
+			"http://example.org/inline": [{"@list": [{"@id": "http://example.org/d"}]}]
+		}]`,
+	},
+	{
+		name: "graph",
+		in: `{
+			"@context": {"@vocab": "http://example.org/"},
+			"@graph": [
+				{"@id": "http://example.org/a", "name": "A"},
+				"free-floating",
+				{"@id": "http://example.org/b"}
+			]
+		}`,
+		out: `[
+			{"@id": "http://example.org/a", "http://example.org/name": [{"@value": "A"}]}
+		]`,
+	},
+	{
+		name: "typed value",
+		in: `{
+			"@context": {
+				"xsd": "http://www.w3.org/2001/XMLSchema#",
+				"modified": {"@id": "http://purl.org/dc/terms/modified", "@type": "xsd:dateTime"}
+			},
+			"modified": "2010-05-29T14:17:39+02:00",
+			"http://example.org/n": 42
+		}`,
+		out: `[{
+			"http://purl.org/dc/terms/modified": [{
+				"@value": "2010-05-29T14:17:39+02:00",
+				"@type": "http://www.w3.org/2001/XMLSchema#dateTime"
+			}],
+			"http://example.org/n": [{"@value": 42}]
+		}]`,
+	},
+	{
+		name: "direction",
+		in: exampleDirection,
+		out: `[{
+			"http://example.org/title": [{"@value": "كتاب", "@language": "ar", "@direction": "rtl"}],
+			"http://example.org/english": [{"@value": "Book", "@language": "en", "@direction": "ltr"}],
+			"http://example.org/code": [{"@value": "B-1"}],
+			"http://example.org/other": [{"@value": "ספר", "@language": "he", "@direction": "rtl"}]
+		}]`,
+	},
+	{
+		name: "container maps",
+		in: exampleContainerMaps,
+		out: `[{
+			"http://example.org/title": [
+				{"@value": "Title"},
+				{"@value": "The Title", "@language": "en"},
+				{"@value": "Le Titre", "@language": "fr"}
+			],
+			"http://example.org/post": [
+				{"@id": "http://example.org/posts/2"},
+				{"@id": "http://example.org/posts/1", "@index": "first", "http://example.org/body": [{"@value": "Hello"}]}
+			],
+			"http://example.org/author": [
+				{"@id": "http://example.org/alice", "http://example.org/name": [{"@value": "Alice"}]}
+			],
+			"http://example.org/thing": [
+				{"@id": "http://example.org/book", "@type": ["http://example.org/Book"]},
+				{"@id": "http://example.org/film", "@type": ["http://example.org/Film", "http://example.org/Thing"]}
+			]
+		}]`,
+	},
+	{
+		name: "included",
+		in: exampleIncluded,
+		out: `[{
+			"@id": "http://example.org/articles/1",
+			"http://example.org/title": [{"@value": "JSON-LD"}],
+			"http://example.org/author": [{"@id": "http://example.org/people/9"}],
+			"http://example.org/publisher": [{"@id": "http://example.org/orgs/1"}],
+			"@included": [
+				{"@id": "http://example.org/people/9", "http://example.org/name": [{"@value": "Dan"}]},
+				{"@id": "http://example.org/orgs/1", "http://example.org/name": [{"@value": "W3C"}]}
+			]
+		}]`,
+	},
+	{
+		name: "JSON literal",
+		in: exampleJSONLiteral,
+		out: `[{
+			"@id": "http://example.org/s",
+			"http://example.org/config": [{
+				"@value": {"b": [1, 1e21, 0.5], "a": null, "c": "x"},
+				"@type": "@json"
+			}]
+		}]`,
+	},
+	{
+		name: "scoped contexts",
+		in: exampleScopedContexts,
+		out: exampleScopedContextsExpanded,
+	},
+	{
+		name: "top-level value object",
+		in: `{"@value": "x"}`,
+		out: `[]`,
+	},
+	{
+		name: "value object in @graph",
+		in: `{
+			"@graph": [
+				{"@value": "x"},
+				{"@id": "http://example.org/a", "http://example.org/p": "y"}
+			]
+		}`,
+		out: `[{
+			"@id": "http://example.org/a",
+			"http://example.org/p": [{"@value": "y"}]
+		}]`,
+	},
+	{
+		name: "type map in a type-scoped context",
+		in: `{
+			"@context": {
+				"@vocab": "http://example.org/",
+				"Foo": {"@context": {"foo": "http://foo.example/"}},
+				"byType": {"@container": "@type"}
+			},
+			"@type": "Foo",
+			"byType": {"Bar": {"@id": "foo:bob"}}
+		}`,
+		out: `[{
+			"@type": ["http://example.org/Foo"],
+			"http://example.org/byType": [{
+				"@id": "foo:bob",
+				"@type": ["http://example.org/Bar"]
+			}]
+		}]`,
+	},
+	{
+		name: "nest",
+		in: exampleNest,
+		out: `[{
+			"@id": "http://example.org/resource",
+			"http://example.org/name": [{"@value": "Resource"}],
+			"http://example.org/mainLabel": [{"@value": "Main label"}],
+			"http://example.org/otherLabel": [{"@value": "Other label"}]
+		}]`,
+	},
+	{
+		name: "protected",
+		in: `{
+			"@context": [
+				{
+					"@protected": true,
+					"name": "http://schema.org/name",
+					"knows": {
+						"@id": "http://schema.org/knows",
+						"@context": {"name": "http://xmlns.com/foaf/0.1/name"}
+					}
+				},
+				{"name": "http://schema.org/name"}
+			],
+			"name": "Alice",
+			"knows": {"name": "Bob"}
+		}`,
+		out: `[{
+			"http://schema.org/name": [{"@value": "Alice"}],
+			"http://schema.org/knows": [{
+				"http://xmlns.com/foaf/0.1/name": [{"@value": "Bob"}]
+			}]
+		}]`,
+	},
+	{
+		name: "version",
+		in: `{
+			"@context": {"@version": 1.1, "p": {"@id": "http://example.org/p", "@container": "@id"}},
+			"p": {"http://example.org/a": {"http://example.org/q": "value"}}
+		}`,
+		out: `[{
+			"http://example.org/p": [{
+				"@id": "http://example.org/a",
+				"http://example.org/q": [{"@value": "value"}]
+			}]
+		}]`,
+	},
+	{
+		name: "base",
+		in: `{
+			"@context": [
+				{"@base": "http://example.org/a/b"},
+				{"@base": "c/"}
+			],
+			"@id": "../d",
+			"http://example.org/p": [{"@id": "e/./f/../g"}, {"@id": "#h"}]
+		}`,
+		out: `[{
+			"@id": "http://example.org/a/d",
+			"http://example.org/p": [
+				{"@id": "http://example.org/a/c/e/g"},
+				{"@id": "http://example.org/a/c/#h"}
+			]
+		}]`,
+	},
+	{
+		name: "null base",
+		in: `{
+			"@context": [{"@base": "http://example.org/"}, {"@base": null}],
+			"@id": "relative",
+			"http://example.org/p": "value"
+		}`,
+		out: `[{
+			"@id": "relative",
+			"http://example.org/p": [{"@value": "value"}]
+		}]`,
+	},
+	{
+		name: "keyword aliases",
+		in: `{
+			"@context": {
+				"ex": "http://example.org/",
+				"id": "@id",
+				"type": "@type",
+				"value": "@value",
+				"lang": "@language",
+				"@type": {"@container": "@set", "@protected": true}
+			},
+			"id": "ex:a",
+			"type": "ex:T",
+			"ex:label": {"value": "label", "lang": "en"}
+		}`,
+		out: `[{
+			"@id": "http://example.org/a",
+			"@type": ["http://example.org/T"],
+			"http://example.org/label": [{"@value": "label", "@language": "en"}]
+		}]`,
+	},
+	{
+		name: "reverse",
+		in: `{
+			"@context": {
+				"ex": "http://example.org/",
+				"children": {"@reverse": "ex:parent", "@type": "@id"}
+			},
+			"@id": "ex:alice",
+			"children": ["ex:bob", "ex:carol"],
+			"@reverse": {
+				"ex:child": {"@id": "ex:dave"}
+			}
+		}`,
+		out: `[{
+			"@id": "http://example.org/alice",
+			"@reverse": {
+				"http://example.org/parent": [
+					{"@id": "http://example.org/bob"},
+					{"@id": "http://example.org/carol"}
+				],
+				"http://example.org/child": [{"@id": "http://example.org/dave"}]
+			}
+		}]`,
+	},
+}
+
+var exampleIncluded = `{
+	"@context": {"@vocab": "http://example.org/"},
+	"@id": "http://example.org/articles/1",
+	"title": "JSON-LD",
+	"author": {"@id": "http://example.org/people/9"},
+	"publisher": {"@id": "http://example.org/orgs/1"},
+	"@included": [
+		{"@id": "http://example.org/people/9", "name": "Dan"},
+		{"@id": "http://example.org/orgs/1", "name": "W3C"}
+	]
+}`
+
+var exampleScopedContexts = `{
+	"@context": {
+		"@vocab": "http://example.org/",
+		"author": {
+			"@id": "http://example.org/author",
+			"@context": {"name": "http://xmlns.com/foaf/0.1/name"}
+		},
+		"reviewer": {
+			"@id": "http://example.org/reviewer",
+			"@context": {"@propagate": false, "name": "http://schema.org/name"}
+		},
+		"Book": {
+			"@id": "http://example.org/Book",
+			"@context": {"title": "http://purl.org/dc/terms/title"}
+		}
+	},
+	"@type": "Book",
+	"title": "JSON-LD",
+	"author": {
+		"name": "Dan",
+		"title": "Dr",
+		"knows": {"name": "Alice"}
+	},
+	"reviewer": {
+		"name": "Eve",
+		"knows": {"name": "Bob"}
+	}
+}`
+
+const exampleScopedContextsExpanded = `[{
+	"@type": ["http://example.org/Book"],
+	"http://purl.org/dc/terms/title": [{"@value": "JSON-LD"}],
+	"http://example.org/author": [{
+		"http://xmlns.com/foaf/0.1/name": [{"@value": "Dan"}],
+		"http://example.org/title": [{"@value": "Dr"}],
+		"http://example.org/knows": [{
+			"http://xmlns.com/foaf/0.1/name": [{"@value": "Alice"}]
+		}]
+	}],
+	"http://example.org/reviewer": [{
+		"http://schema.org/name": [{"@value": "Eve"}],
+		"http://example.org/knows": [{
+			"http://example.org/name": [{"@value": "Bob"}]
+		}]
+	}]
+}]`
+
+var exampleNest = `{
+	"@context": {
+		"@vocab": "http://example.org/",
+		"labels": "@nest",
+		"main_label": {"@id": "http://example.org/mainLabel", "@nest": "labels"},
+		"other_label": {"@id": "http://example.org/otherLabel", "@nest": "labels"}
+	},
+	"@id": "http://example.org/resource",
+	"name": "Resource",
+	"labels": {
+		"main_label": "Main label",
+		"other_label": "Other label"
+	}
+}`
+
+var exampleDirection = `{
+	"@context": {
+		"@vocab": "http://example.org/",
+		"@language": "ar",
+		"@direction": "rtl",
+		"english": {"@id": "http://example.org/english", "@language": "en", "@direction": "ltr"},
+		"code": {"@id": "http://example.org/code", "@language": null, "@direction": null}
+	},
+	"title": "كتاب",
+	"english": "Book",
+	"code": "B-1",
+	"other": {"@value": "ספר", "@language": "he", "@direction": "rtl"}
+}`
+
+var exampleContainerMaps = `{
+	"@context": {
+		"@vocab": "http://example.org/",
+		"title": {"@id": "http://example.org/title", "@container": "@language"},
+		"posts": {"@id": "http://example.org/post", "@container": "@index"},
+		"authors": {"@id": "http://example.org/author", "@container": "@id"},
+		"things": {"@id": "http://example.org/thing", "@container": "@type"}
+	},
+	"title": {"en": "The Title", "fr": ["Le Titre"], "@none": "Title"},
+	"posts": {
+		"first": {"@id": "http://example.org/posts/1", "body": "Hello"},
+		"@none": {"@id": "http://example.org/posts/2"}
+	},
+	"authors": {
+		"http://example.org/alice": {"name": "Alice"}
+	},
+	"things": {
+		"Book": "http://example.org/book",
+		"Film": {"@id": "http://example.org/film", "@type": "Thing"}
+	}
+}`
+
+func TestExpand(t *testing.T) {
+	for _, test := range expandTests {
+		var in, want interface{}
+		if err := json.Unmarshal([]byte(test.in), &in); err != nil {
+			t.Fatalf("%v: json.Unmarshal(in) = %v", test.name, err)
+		}
+		if err := json.Unmarshal([]byte(test.out), &want); err != nil {
+			t.Fatalf("%v: json.Unmarshal(out) = %v", test.name, err)
+		}
+
+		out, err := Expand(in, nil)
+		if err != nil {
+			t.Errorf("%v: Expand() = %v", test.name, err)
+			continue
+		}
+
+		got := roundTripJSON(t, out)
+		if !reflect.DeepEqual(got, want) {
+			b, _ := json.Marshal(out)
+			t.Errorf("%v: Expand() = %v, want %v", test.name, string(b), test.out)
+		}
+	}
+}
+
+func TestExpand_error(t *testing.T) {
+	in := map[string]interface{}{
+		"@context": map[string]interface{}{
+			"@id": "http://example.org/id",
+		},
+	}
+	_, err := Expand(in, nil)
+	if err, ok := err.(*Error); !ok || err.Code != "keyword redefinition" {
+		t.Errorf("Expand() = %v, want a keyword redefinition error", err)
+	}
+}
+
+func TestExpand_protectedExpandContext(t *testing.T) {
+	opts := &Options{
+		ExpandContext: &Context{
+			Terms: map[string]*Resource{
+				"name": {ID: "http://schema.org/name", Props: Props{"@protected": {true}}},
+			},
+		},
+	}
+
+	var in interface{}
+	if err := json.Unmarshal([]byte(`{"@context": {"name": "http://schema.org/name"}, "name": "Alice"}`), &in); err != nil {
+		t.Fatalf("json.Unmarshal() = %v", err)
+	}
+	if _, err := Expand(in, opts); err != nil {
+		t.Errorf("Expand() = %v, want an identical redefinition to be allowed", err)
+	}
+
+	if err := json.Unmarshal([]byte(`{"@context": {"name": "http://example.org/name"}, "name": "Alice"}`), &in); err != nil {
+		t.Fatalf("json.Unmarshal() = %v", err)
+	}
+	_, err := Expand(in, opts)
+	if err, ok := err.(*Error); !ok || err.Code != "protected term redefinition" {
+		t.Errorf("Expand() = %v, want a protected term redefinition error", err)
+	}
+}
+
+func TestExpand_import(t *testing.T) {
+	docs := map[string]string{
+		"http://example.org/context.jsonld": `{"@context": {
+			"@vocab": "http://schema.org/",
+			"name": {"@id": "http://schema.org/name", "@protected": true},
+			"knows": {"@id": "http://schema.org/knows", "@type": "@id"}
+		}}`,
+		"http://example.org/nested.jsonld": `{"@context": {"@import": "http://example.org/context.jsonld"}}`,
+		"http://example.org/array.jsonld": `{"@context": [{"@vocab": "http://schema.org/"}]}`,
+	}
+	opts := &Options{
+		FetchDocument: func(url string) (interface{}, error) {
+			raw, ok := docs[url]
+			if !ok {
+				return nil, fmt.Errorf("unknown document %q", url)
+			}
+			var doc interface{}
+			err := json.Unmarshal([]byte(raw), &doc)
+			return doc, err
+		},
+	}
+
+	var in interface{}
+	err := json.Unmarshal([]byte(`{
+		"@context": {
+			"@import": "http://example.org/context.jsonld",
+			"name": "http://xmlns.com/foaf/0.1/name"
+		},
+		"name": "Alice",
+		"knows": "http://example.org/bob",
+		"description": "Someone"
+	}`), &in)
+	if err != nil {
+		t.Fatalf("json.Unmarshal() = %v", err)
+	}
+
+	out, err := Expand(in, opts)
+	if err != nil {
+		t.Fatalf("Expand() = %v", err)
+	}
+
+	var want interface{}
+	err = json.Unmarshal([]byte(`[{
+		"http://xmlns.com/foaf/0.1/name": [{"@value": "Alice"}],
+		"http://schema.org/knows": [{"@id": "http://example.org/bob"}],
+		"http://schema.org/description": [{"@value": "Someone"}]
+	}]`), &want)
+	if err != nil {
+		t.Fatalf("json.Unmarshal() = %v", err)
+	}
+	if got := roundTripJSON(t, out); !reflect.DeepEqual(got, want) {
+		b, _ := json.Marshal(out)
+		t.Errorf("Expand() = %v, want %v", string(b), want)
+	}
+
+	errorTests := map[string]string{
+		"http://example.org/nested.jsonld": "invalid context entry",
+		"http://example.org/array.jsonld": "invalid remote context",
+	}
+	for url, code := range errorTests {
+		in := map[string]interface{}{
+			"@context": map[string]interface{}{"@import": url},
+		}
+		_, err := Expand(in, opts)
+		if err, ok := err.(*Error); !ok || err.Code != code {
+			t.Errorf("Expand(%v) = %v, want a %v error", url, err, code)
+		}
+	}
+}
+
+func TestExpand_remoteNull(t *testing.T) {
+	opts := &Options{
+		FetchContext: func(url string) (*Context, error) {
+			if url != "http://example.org/context.jsonld" {
+				return nil, fmt.Errorf("unknown context %q", url)
+			}
+			return newProcessor(nil).parseContext(nil, map[string]interface{}{
+				"@language": nil,
+				"@direction": nil,
+				"@vocab": nil,
+				"name": "http://schema.org/name",
+			})
+		},
+	}
+
+	var in interface{}
+	err := json.Unmarshal([]byte(`{
+		"@context": [
+			{"@vocab": "http://example.org/", "@language": "en", "@direction": "ltr"},
+			"http://example.org/context.jsonld"
+		],
+		"name": "Alice",
+		"description": "Someone"
+	}`), &in)
+	if err != nil {
+		t.Fatalf("json.Unmarshal() = %v", err)
+	}
+
+	out, err := Expand(in, opts)
+	if err != nil {
+		t.Fatalf("Expand() = %v", err)
+	}
+
+	var want interface{}
+	err = json.Unmarshal([]byte(`[{
+		"http://schema.org/name": [{"@value": "Alice"}]
+	}]`), &want)
+	if err != nil {
+		t.Fatalf("json.Unmarshal() = %v", err)
+	}
+	if got := roundTripJSON(t, out); !reflect.DeepEqual(got, want) {
+		b, _ := json.Marshal(out)
+		t.Errorf("Expand() = %v, want %v", string(b), want)
+	}
+}
+
+func TestExpand_base(t *testing.T) {
+	opts := &Options{Base: "http://example.org/people/index.jsonld"}
+
+	var in interface{}
+	err := json.Unmarshal([]byte(`[
+		{"@id": "#alice", "http://schema.org/knows": {"@id": "bob.jsonld#me"}},
+		{"@context": {"@base": null}, "@id": "#carol", "http://schema.org/name": "Carol"},
+		{"@context": [{"@base": "http://example.org/"}, null], "@id": "#dave", "http://schema.org/name": "Dave"}
+	]`), &in)
+	if err != nil {
+		t.Fatalf("json.Unmarshal() = %v", err)
+	}
+
+	out, err := Expand(in, opts)
+	if err != nil {
+		t.Fatalf("Expand() = %v", err)
+	}
+
+	var want interface{}
+	err = json.Unmarshal([]byte(`[
+		{
+			"@id": "http://example.org/people/index.jsonld#alice",
+			"http://schema.org/knows": [{"@id": "http://example.org/people/bob.jsonld#me"}]
+		},
+		{"@id": "#carol", "http://schema.org/name": [{"@value": "Carol"}]},
+		{"@id": "http://example.org/people/index.jsonld#dave", "http://schema.org/name": [{"@value": "Dave"}]}
+	]`), &want)
+	if err != nil {
+		t.Fatalf("json.Unmarshal() = %v", err)
+	}
+	if got := roundTripJSON(t, out); !reflect.DeepEqual(got, want) {
+		b, _ := json.Marshal(out)
+		t.Errorf("Expand() = %v, want %v", string(b), want)
+	}
+}
+
+func TestExpand_processingMode(t *testing.T) {
+	opts := &Options{ProcessingMode: "json-ld-1.0"}
+
+	errorTests := []struct{
+		name string
+		in string
+		code string
+	}{
+		{
+			name: "version",
+			in: `{"@context": {"@version": 1.1}}`,
+			code: "processing mode conflict",
+		},
+		{
+			name: "propagate",
+			in: `{"@context": {"@propagate": true}}`,
+			code: "invalid context entry",
+		},
+		{
+			name: "protected",
+			in: `{"@context": {"@protected": true}}`,
+			code: "invalid context entry",
+		},
+		{
+			name: "scoped context",
+			in: `{"@context": {"p": {"@id": "http://example.org/p", "@context": {}}}}`,
+			code: "invalid term definition",
+		},
+		{
+			name: "prefix",
+			in: `{"@context": {"ex": {"@id": "http://example.org/", "@prefix": true}}}`,
+			code: "invalid term definition",
+		},
+		{
+			name: "id map",
+			in: `{"@context": {"p": {"@id": "http://example.org/p", "@container": "@id"}}}`,
+			code: "invalid container mapping",
+		},
+		{
+			name: "multiple containers",
+			in: `{"@context": {"p": {"@id": "http://example.org/p", "@container": ["@index", "@set"]}}}`,
+			code: "invalid container mapping",
+		},
+		{
+			name: "JSON type",
+			in: `{"@context": {"p": {"@id": "http://example.org/p", "@type": "@json"}}}`,
+			code: "invalid type mapping",
+		},
+		{
+			name: "type redefinition",
+			in: `{"@context": {"@type": {"@container": "@set"}}}`,
+			code: "keyword redefinition",
+		},
+	}
+	for _, test := range errorTests {
+		var in interface{}
+		if err := json.Unmarshal([]byte(test.in), &in); err != nil {
+			t.Fatalf("%v: json.Unmarshal(in) = %v", test.name, err)
+		}
+		_, err := Expand(in, opts)
+		if err, ok := err.(*Error); !ok || err.Code != test.code {
+			t.Errorf("%v: Expand() = %v, want a %v error", test.name, err, test.code)
+		}
+	}
+
+	// JSON-LD 1.1 keywords are ignored
+	var in, want interface{}
+	err := json.Unmarshal([]byte(`{
+		"@id": "http://example.org/a",
+		"http://example.org/p": {"@value": "x", "@direction": "rtl"},
+		"@included": [{"@id": "http://example.org/b", "http://example.org/p": "y"}]
+	}`), &in)
+	if err != nil {
+		t.Fatalf("json.Unmarshal(in) = %v", err)
+	}
+	err = json.Unmarshal([]byte(`[{
+		"@id": "http://example.org/a",
+		"http://example.org/p": [{"@value": "x"}]
+	}]`), &want)
+	if err != nil {
+		t.Fatalf("json.Unmarshal(out) = %v", err)
+	}
+	out, err := Expand(in, opts)
+	if err != nil {
+		t.Fatalf("Expand() = %v", err)
+	}
+	if got := roundTripJSON(t, out); !reflect.DeepEqual(got, want) {
+		b, _ := json.Marshal(out)
+		t.Errorf("Expand() = %v, want %v", string(b), want)
+	}
+
+	// Contexts processed in JSON-LD 1.1 mode are rejected
+	for _, raw := range []string{
+		`{"@propagate": true}`,
+		`{"@version": 1.1}`,
+		`{"p": {"@id": "http://example.org/p", "@protected": true}}`,
+	} {
+		var v interface{}
+		if err := json.Unmarshal([]byte(raw), &v); err != nil {
+			t.Fatalf("json.Unmarshal(%v) = %v", raw, err)
+		}
+		ctx, err := newProcessor(nil).parseContext(nil, v)
+		if err != nil {
+			t.Fatalf("parseContext(%v) = %v", raw, err)
+		}
+		_, err = Expand(in, &Options{ProcessingMode: "json-ld-1.0", ExpandContext: ctx})
+		if err, ok := err.(*Error); !ok || err.Code != "processing mode conflict" {
+			t.Errorf("Expand(ExpandContext: %v) = %v, want a processing mode conflict error", raw, err)
+		}
+	}
+}
+
+func TestExpand_errorCodes(t *testing.T) {
+	tests := []struct{
+		name string
+		in string
+		code string
+	}{
+		{
+			name: "value object",
+			in: `{"@reverse": {"http://example.org/p": "literal"}}`,
+			code: "invalid reverse property value",
+		},
+		{
+			name: "not a map",
+			in: `{"@reverse": "http://example.org/p"}`,
+			code: "invalid @reverse value",
+		},
+		{
+			name: "@id and @reverse",
+			in: `{"@context": {"p": {"@id": "http://example.org/p", "@reverse": "http://example.org/q"}}}`,
+			code: "invalid reverse property",
+		},
+		{
+			name: "direction",
+			in: `{"http://example.org/p": {"@value": "x", "@direction": "up"}}`,
+			code: "invalid base direction",
+		},
+		{
+			name: "direction and type",
+			in: `{"http://example.org/p": {"@value": "x", "@direction": "ltr", "@type": "http://example.org/t"}}`,
+			code: "invalid value object",
+		},
+		{
+			name: "language map value",
+			in: `{"@context": {"p": {"@id": "http://example.org/p", "@container": "@language"}}, "p": {"en": 42}}`,
+			code: "invalid language map value",
+		},
+		{
+			name: "multiple map containers",
+			in: `{"@context": {"p": {"@id": "http://example.org/p", "@container": ["@index", "@language"]}}}`,
+			code: "invalid container mapping",
+		},
+		{
+			name: "list container",
+			in: `{"@context": {"p": {"@reverse": "http://example.org/p", "@container": "@list"}}}`,
+			code: "invalid reverse property",
+		},
+		{
+			name: "nest term definition",
+			in: `{"@context": {"p": {"@id": "http://example.org/p", "@nest": "@id"}}}`,
+			code: "invalid @nest value",
+		},
+		{
+			name: "object value",
+			in: `{"http://example.org/p": {"@value": {"a": 1}}}`,
+			code: "invalid value object value",
+		},
+		{
+			name: "scoped context",
+			in: `{"@context": {"p": {"@id": "http://example.org/p", "@context": {"@vocab": 42}}}}`,
+			code: "invalid scoped context",
+		},
+		{
+			name: "propagate",
+			in: `{"@context": {"@propagate": "no"}}`,
+			code: "invalid @propagate value",
+		},
+		{
+			name: "included value",
+			in: `{"@included": {"@value": "x"}}`,
+			code: "invalid @included value",
+		},
+		{
+			name: "nest value",
+			in: `{"@nest": "http://example.org/p"}`,
+			code: "invalid @nest value",
+		},
+		{
+			name: "import value",
+			in: `{"@context": {"@import": 42}}`,
+			code: "invalid @import value",
+		},
+		{
+			name: "import without loader",
+			in: `{"@context": {"@import": "http://example.org/context.jsonld"}}`,
+			code: "loading remote context failed",
+		},
+		{
+			name: "version",
+			in: `{"@context": {"@version": 1.0}}`,
+			code: "invalid @version value",
+		},
+		{
+			name: "relative base",
+			in: `{"@context": {"@base": "relative/"}}`,
+			code: "invalid base IRI",
+		},
+		{
+			name: "type redefinition",
+			in: `{"@context": {"@type": {"@container": "@list"}}}`,
+			code: "keyword redefinition",
+		},
+		{
+			name: "protected value",
+			in: `{"@context": {"@protected": "yes"}}`,
+			code: "invalid @protected value",
+		},
+		{
+			name: "protected term redefinition",
+			in: `{"@context": [
+				{"@protected": true, "name": "http://schema.org/name"},
+				{"name": "http://example.org/name"}
+			]}`,
+			code: "protected term redefinition",
+		},
+		{
+			name: "protected term redefinition in type-scoped context",
+			in: `{
+				"@context": {
+					"name": {"@id": "http://schema.org/name", "@protected": true},
+					"Person": {"@id": "http://schema.org/Person", "@context": {"name": "http://example.org/name"}}
+				},
+				"@type": "Person"
+			}`,
+			code: "protected term redefinition",
+		},
+		{
+			name: "protected context nullification",
+			in: `{"@context": [
+				{"@protected": true, "name": "http://schema.org/name"},
+				null
+			]}`,
+			code: "invalid context nullification",
+		},
+	}
+	for _, test := range tests {
+		var in interface{}
+		if err := json.Unmarshal([]byte(test.in), &in); err != nil {
+			t.Fatalf("%v: json.Unmarshal(in) = %v", test.name, err)
+		}
+		_, err := Expand(in, nil)
+		if err, ok := err.(*Error); !ok || err.Code != test.code {
+			t.Errorf("%v: Expand() = %v, want a %v error", test.name, err, test.code)
+		}
+	}
+}
+
+// roundTripJSON marshals and unmarshals v, so that it can be compared with a
+// value decoded by the encoding/json package.
+func roundTripJSON(t *testing.T, v interface{}) interface{} {
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("json.Marshal() = %v", err)
+	}
+	var out interface{}
+	if err := json.Unmarshal(b, &out); err != nil {
+		t.Fatalf("json.Unmarshal() = %v", err)
+	}
+	return out
+}
@@ -0,0 +1,277 @@
+package jsonld
+
+import (
+	"reflect"
+	"sort"
+)
+
+// Flatten flattens a JSON-LD document, as defined in
+// https://www.w3.org/TR/json-ld11-api/#flattening-algorithm.
+//
+// All node objects are collected in a top-level @graph entry. Nodes with the
+// same identifier are merged, and blank node identifiers are assigned to nodes
+// without one. If ctx is non-nil, the result is compacted with ctx.
+func Flatten(input interface{}, ctx *Context, opts *Options) (map[string]interface{}, error) {
+	p := newProcessor(opts)
+	expanded, err := p.expandDocument(input)
+	if err != nil {
+		return nil, err
+	}
+
+	nm := newNodeMap()
+	if err := nm.generate(expanded, "@default", "", "", nil); err != nil {
+		return nil, err
+	}
+	flattened := nm.flatten()
+
+	if ctx == nil {
+		return map[string]interface{}{"@graph": flattened}, nil
+	}
+
+	active := (&Context{Base: p.opts.Base}).newChild(ctx)
+	compacted, err := p.compact(active, "", flattened)
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]interface{}{
+		p.compactIRI(active, "@graph", nil, true, false): toArray(compacted),
+	}
+	if formatted := formatContext(ctx); formatted != nil {
+		result["@context"] = formatted
+	}
+	return result, nil
+}
+
+// nodeMap maps graph names to node identifiers to node objects, as defined in
+// https://www.w3.org/TR/json-ld11-api/#node-map-generation.
+type nodeMap struct {
+	graphs map[string]map[string]map[string]interface{}
+	issuer *IdentifierIssuer
+}
+
+func newNodeMap() *nodeMap {
+	return &nodeMap{
+		graphs: map[string]map[string]map[string]interface{}{
+			"@default": make(map[string]map[string]interface{}),
+		},
+		issuer: NewIdentifierIssuer("_:b"),
+	}
+}
+
+func (nm *nodeMap) graph(name string) map[string]map[string]interface{} {
+	g, ok := nm.graphs[name]
+	if !ok {
+		g = make(map[string]map[string]interface{})
+		nm.graphs[name] = g
+	}
+	return g
+}
+
+// addUniqueValue adds v to the values of the entry k of m, if it isn't already
+// present.
+func addUniqueValue(m map[string]interface{}, k string, v interface{}) {
+	values := toArray(m[k])
+	for _, vv := range values {
+		if reflect.DeepEqual(vv, v) {
+			m[k] = values
+			return
+		}
+	}
+	m[k] = append(values, v)
+}
+
+// generate adds the nodes contained in an expanded element to the node map.
+// graph is the active graph, subject and prop are the active subject and
+// property. subject is either a node identifier, or a node reference if prop
+// is a reverse property. If list is non-nil, values are appended to it.
+func (nm *nodeMap) generate(element interface{}, graph string, subject interface{}, prop string, list map[string]interface{}) error {
+	if a, ok := element.([]interface{}); ok {
+		for _, item := range a {
+			if err := nm.generate(item, graph, subject, prop, list); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	m, ok := element.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	g := nm.graph(graph)
+
+	var node map[string]interface{}
+	if id, ok := subject.(string); ok && id != "" {
+		node = g[id]
+	}
+
+	if t, ok := m["@type"]; ok {
+		var types []interface{}
+		for _, t := range toArray(t) {
+			if s, ok := t.(string); ok && isBlankNodeID(s) {
+				t = nm.issuer.Issue(s)
+			}
+			types = append(types, t)
+		}
+		if _, ok := t.(string); ok {
+			m["@type"] = types[0]
+		} else {
+			m["@type"] = types
+		}
+	}
+
+	if _, ok := m["@value"]; ok {
+		if list == nil {
+			addUniqueValue(node, prop, m)
+		} else {
+			addValue(list, "@list", m, true)
+		}
+		return nil
+	}
+
+	if l, ok := m["@list"]; ok {
+		result := map[string]interface{}{"@list": []interface{}{}}
+		if err := nm.generate(l, graph, subject, prop, result); err != nil {
+			return err
+		}
+		if list == nil {
+			addValue(node, prop, result, true)
+		} else {
+			addValue(list, "@list", result, true)
+		}
+		return nil
+	}
+
+	id, _ := m["@id"].(string)
+	if id == "" || isBlankNodeID(id) {
+		id = nm.issuer.Issue(id)
+	}
+
+	n, ok := g[id]
+	if !ok {
+		n = map[string]interface{}{"@id": id}
+		g[id] = n
+	}
+
+	if ref, ok := subject.(map[string]interface{}); ok {
+		addUniqueValue(n, prop, ref)
+	} else if prop != "" {
+		ref := map[string]interface{}{"@id": id}
+		if list == nil {
+			addUniqueValue(node, prop, ref)
+		} else {
+			addValue(list, "@list", ref, true)
+		}
+	}
+
+	if t, ok := m["@type"]; ok {
+		for _, t := range toArray(t) {
+			addUniqueValue(n, "@type", t)
+		}
+	}
+
+	if index, ok := m["@index"]; ok {
+		if old, ok := n["@index"]; ok && old != index {
+			return errorf("conflicting indexes", "%q", id)
+		}
+		n["@index"] = index
+	}
+
+	if reverse, ok := m["@reverse"].(map[string]interface{}); ok {
+		ref := map[string]interface{}{"@id": id}
+		for _, k := range sortedKeys(reverse) {
+			if err := nm.generate(reverse[k], graph, ref, k, nil); err != nil {
+				return err
+			}
+		}
+	}
+
+	if v, ok := m["@included"]; ok {
+		// Included nodes are added to the active graph, without any link to
+		// this node
+		if err := nm.generate(v, graph, "", "", nil); err != nil {
+			return err
+		}
+	}
+
+	if v, ok := m["@graph"]; ok {
+		nm.graph(id)
+		if err := nm.generate(v, id, "", "", nil); err != nil {
+			return err
+		}
+	}
+
+	for _, k := range sortedKeys(m) {
+		if isKeyword(k) {
+			continue
+		}
+
+		p := k
+		if isBlankNodeID(p) {
+			p = nm.issuer.Issue(p)
+		}
+		if _, ok := n[p]; !ok {
+			n[p] = []interface{}{}
+		}
+		if err := nm.generate(m[k], graph, id, p, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func sortedNodeIDs(g map[string]map[string]interface{}) []string {
+	ids := make([]string, 0, len(g))
+	for id := range g {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// isNodeReference checks whether a node object only contains an @id entry.
+func isNodeReference(n map[string]interface{}) bool {
+	_, ok := n["@id"]
+	return ok && len(n) == 1
+}
+
+// flatten returns the flattened list of nodes of the default graph. Named
+// graphs are stored in the @graph entry of their graph name node.
+func (nm *nodeMap) flatten() []interface{} {
+	defaultGraph := nm.graphs["@default"]
+
+	names := make([]string, 0, len(nm.graphs))
+	for name := range nm.graphs {
+		if name != "@default" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		entry, ok := defaultGraph[name]
+		if !ok {
+			entry = map[string]interface{}{"@id": name}
+			defaultGraph[name] = entry
+		}
+
+		g := nm.graphs[name]
+		nodes := []interface{}{}
+		for _, id := range sortedNodeIDs(g) {
+			if n := g[id]; !isNodeReference(n) {
+				nodes = append(nodes, n)
+			}
+		}
+		entry["@graph"] = nodes
+	}
+
+	flattened := []interface{}{}
+	for _, id := range sortedNodeIDs(defaultGraph) {
+		if n := defaultGraph[id]; !isNodeReference(n) {
+			flattened = append(flattened, n)
+		}
+	}
+	return flattened
+}
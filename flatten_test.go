@@ -0,0 +1,169 @@
+package jsonld
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+var flattenTests = []struct{
+	name string
+	in string
+	ctx string
+	out string
+}{
+	{
+		name: "merge nodes",
+		in: `{
+			"@context": {
+				"@vocab": "http://xmlns.com/foaf/0.1/",
+				"knows": {"@type": "@id"}
+			},
+			"@id": "http://me.markus-lanthaler.com/",
+			"name": "Markus Lanthaler",
+			"knows": [
+				{
+					"@id": "http://manu.sporny.org/about#manu",
+					"name": "Manu Sporny"
+				},
+				{
+					"name": "Dave Longley",
+					"knows": {
+						"@id": "http://manu.sporny.org/about#manu",
+						"homepage": "http://manu.sporny.org/"
+					}
+				}
+			]
+		}`,
+		out: `{
+			"@graph": [
+				{
+					"@id": "_:b0",
+					"http://xmlns.com/foaf/0.1/name": [{"@value": "Dave Longley"}],
+					"http://xmlns.com/foaf/0.1/knows": [{"@id": "http://manu.sporny.org/about#manu"}]
+				},
+				{
+					"@id": "http://manu.sporny.org/about#manu",
+					"http://xmlns.com/foaf/0.1/name": [{"@value": "Manu Sporny"}],
+					"http://xmlns.com/foaf/0.1/homepage": [{"@value": "http://manu.sporny.org/"}]
+				},
+				{
+					"@id": "http://me.markus-lanthaler.com/",
+					"http://xmlns.com/foaf/0.1/name": [{"@value": "Markus Lanthaler"}],
+					"http://xmlns.com/foaf/0.1/knows": [
+						{"@id": "http://manu.sporny.org/about#manu"},
+						{"@id": "_:b0"}
+					]
+				}
+			]
+		}`,
+	},
+	{
+		name: "named graph with context",
+		in: `{
+			"@context": {"@vocab": "http://example.org/"},
+			"@id": "http://example.org/g",
+			"@graph": {
+				"@id": "_:x",
+				"@type": "Thing",
+				"list": {"@list": [{"@id": "_:y", "name": "y"}]}
+			}
+		}`,
+		ctx: `{"@vocab": "http://example.org/"}`,
+		out: `{
+			"@context": {"@vocab": "http://example.org/"},
+			"@graph": [
+				{
+					"@id": "http://example.org/g",
+					"@graph": [
+						{"@id": "_:b0", "@type": "Thing", "list": {"@list": [{"@id": "_:b1"}]}},
+						{"@id": "_:b1", "name": "y"}
+					]
+				}
+			]
+		}`,
+	},
+	{
+		name: "reverse",
+		in: `{
+			"@context": {
+				"ex": "http://example.org/",
+				"children": {"@reverse": "ex:parent"}
+			},
+			"@id": "ex:alice",
+			"ex:name": "Alice",
+			"children": [
+				{"@id": "ex:bob", "ex:name": "Bob"},
+				{"ex:name": "Carol"}
+			]
+		}`,
+		out: `{
+			"@graph": [
+				{
+					"@id": "_:b0",
+					"http://example.org/name": [{"@value": "Carol"}],
+					"http://example.org/parent": [{"@id": "http://example.org/alice"}]
+				},
+				{
+					"@id": "http://example.org/alice",
+					"http://example.org/name": [{"@value": "Alice"}]
+				},
+				{
+					"@id": "http://example.org/bob",
+					"http://example.org/name": [{"@value": "Bob"}],
+					"http://example.org/parent": [{"@id": "http://example.org/alice"}]
+				}
+			]
+		}`,
+	},
+	{
+		name: "included",
+		in: exampleIncluded,
+		out: `{
+			"@graph": [
+				{
+					"@id": "http://example.org/articles/1",
+					"http://example.org/title": [{"@value": "JSON-LD"}],
+					"http://example.org/author": [{"@id": "http://example.org/people/9"}],
+					"http://example.org/publisher": [{"@id": "http://example.org/orgs/1"}]
+				},
+				{
+					"@id": "http://example.org/orgs/1",
+					"http://example.org/name": [{"@value": "W3C"}]
+				},
+				{
+					"@id": "http://example.org/people/9",
+					"http://example.org/name": [{"@value": "Dan"}]
+				}
+			]
+		}`,
+	},
+}
+
+func TestFlatten(t *testing.T) {
+	for _, test := range flattenTests {
+		var in, want interface{}
+		if err := json.Unmarshal([]byte(test.in), &in); err != nil {
+			t.Fatalf("%v: json.Unmarshal(in) = %v", test.name, err)
+		}
+		if err := json.Unmarshal([]byte(test.out), &want); err != nil {
+			t.Fatalf("%v: json.Unmarshal(out) = %v", test.name, err)
+		}
+		var ctx *Context
+		if test.ctx != "" {
+			ctx = parseTestContext(t, test.ctx)
+		}
+
+		out, err := Flatten(in, ctx, nil)
+		if err != nil {
+			t.Errorf("%v: Flatten() = %v", test.name, err)
+			continue
+		}
+
+		got := roundTripJSON(t, out)
+		if !reflect.DeepEqual(got, want) {
+			b, _ := json.Marshal(out)
+			t.Errorf("%v: Flatten() = %v, want %v", test.name, string(b), test.out)
+		}
+	}
+}
@@ -0,0 +1,652 @@
+package jsonld
+
+import (
+	"reflect"
+	"sort"
+)
+
+// Frame frames a JSON-LD document, as defined in
+// https://www.w3.org/TR/json-ld11-framing/#framing-algorithm.
+//
+// The input document and the frame must be values decoded by the
+// encoding/json package. The nodes of the input document matching the frame
+// are embedded into a tree having the shape of the frame, which is then
+// compacted with the context of the frame.
+func Frame(input, frame interface{}, opts *Options) (map[string]interface{}, error) {
+	p := newProcessor(opts)
+
+	expandedInput, err := p.expandDocument(input)
+	if err != nil {
+		return nil, err
+	}
+
+	p.frameExpansion = true
+	expandedFrame, err := p.expandDocument(frame)
+	p.frameExpansion = false
+	if err != nil {
+		return nil, err
+	}
+	if len(expandedFrame) == 0 {
+		expandedFrame = []interface{}{map[string]interface{}{}}
+	} else if len(expandedFrame) > 1 {
+		return nil, errorf("invalid frame", "expected a single frame object")
+	}
+	frameMap, ok := expandedFrame[0].(map[string]interface{})
+	if !ok {
+		return nil, errorf("invalid frame", "")
+	}
+
+	rawFrame, _ := frame.(map[string]interface{})
+	rawCtx := rawFrame["@context"]
+	ctx, err := p.parseContext(&Context{Base: p.opts.Base}, rawCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	nm := newNodeMap()
+	if err := nm.generate(expandedInput, "@default", "", "", nil); err != nil {
+		return nil, err
+	}
+	nm.merge()
+
+	state := &framingState{
+		opts: p.opts,
+		graphs: nm.graphs,
+		uniqueEmbeds: make(map[string]map[string]bool),
+		bnodes: make(map[string][]map[string]interface{}),
+	}
+
+	graph := "@merged"
+	if _, ok := rawFrame["@graph"]; ok {
+		graph = "@default"
+	}
+
+	var results []interface{}
+	subjects := sortedNodeIDs(nm.graphs[graph])
+	if err := state.frame(graph, false, subjects, frameMap, &results, ""); err != nil {
+		return nil, err
+	}
+
+	state.pruneBlankNodeIDs()
+
+	compacted, err := p.compact(ctx, "", results)
+	if err != nil {
+		return nil, err
+	}
+
+	result, ok := compacted.(map[string]interface{})
+	if !ok {
+		result = map[string]interface{}{
+			p.compactIRI(ctx, "@graph", nil, true, false): toArray(compacted),
+		}
+	}
+	result = removePreserve(result).(map[string]interface{})
+	if rawCtx != nil {
+		result["@context"] = rawCtx
+	}
+	return result, nil
+}
+
+// expandFrameKeyword expands keywords in frames, which accept more values
+// than in regular documents. It returns false if the keyword doesn't need
+// special treatment.
+func (p *processor) expandFrameKeyword(ctx *Context, prop, kw string, v interface{}, result map[string]interface{}) (bool, error) {
+	switch kw {
+	case "@id", "@type":
+		var expanded []interface{}
+		for _, item := range toArray(v) {
+			switch item := item.(type) {
+			case string:
+				expanded = append(expanded, expandIRI(ctx, item, kw == "@id", kw == "@type"))
+			case map[string]interface{}:
+				if def, ok := item["@default"].(string); ok && kw == "@type" {
+					item = map[string]interface{}{"@default": expandIRI(ctx, def, true, true)}
+				} else if len(item) != 0 {
+					return true, errorf("invalid frame", "invalid %v value", kw)
+				}
+				expanded = append(expanded, item)
+			default:
+				return true, errorf("invalid frame", "invalid %v value", kw)
+			}
+		}
+		addValue(result, kw, expanded, true)
+	case "@value", "@language":
+		result[kw] = toArray(v)
+	case "@embed", "@explicit", "@omitDefault", "@requireAll":
+		result[kw] = toArray(v)
+	case "@default":
+		if v == "@null" {
+			result[kw] = []interface{}{v}
+			break
+		}
+		ev, err := p.expand(ctx, prop, v)
+		if err != nil {
+			return true, err
+		}
+		result[kw] = toArray(ev)
+	default:
+		return false, nil
+	}
+	return true, nil
+}
+
+// merge merges all graphs of the node map into a new @merged graph, as
+// defined in https://www.w3.org/TR/json-ld11-api/#merge-node-maps.
+func (nm *nodeMap) merge() {
+	merged := nm.graph("@merged")
+
+	names := make([]string, 0, len(nm.graphs))
+	for name := range nm.graphs {
+		if name != "@merged" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		for _, id := range sortedNodeIDs(nm.graphs[name]) {
+			node := nm.graphs[name][id]
+			mergedNode, ok := merged[id]
+			if !ok {
+				mergedNode = map[string]interface{}{"@id": id}
+				merged[id] = mergedNode
+			}
+			for _, k := range sortedKeys(node) {
+				if isKeyword(k) && k != "@type" {
+					mergedNode[k] = node[k]
+					continue
+				}
+				for _, v := range toArray(node[k]) {
+					addUniqueValue(mergedNode, k, v)
+				}
+			}
+		}
+	}
+}
+
+type framingFlags struct {
+	embed string
+	explicit, requireAll, omitDefault bool
+}
+
+type framedSubject struct {
+	graph, id string
+}
+
+type framingState struct {
+	opts *Options
+	graphs map[string]map[string]map[string]interface{}
+	uniqueEmbeds map[string]map[string]bool
+	subjectStack []framedSubject
+	// bnodes contains the output objects identified by each blank node. Nil
+	// entries stand for other references, such as blank node types.
+	bnodes map[string][]map[string]interface{}
+}
+
+func frameFlag(frame map[string]interface{}, k string) (interface{}, bool) {
+	values, ok := frame[k].([]interface{})
+	if !ok || len(values) == 0 {
+		return nil, false
+	}
+	v := values[0]
+	if m, ok := v.(map[string]interface{}); ok {
+		v = m["@value"]
+	}
+	return v, true
+}
+
+func frameBoolFlag(frame map[string]interface{}, k string, def bool) bool {
+	if v, ok := frameFlag(frame, k); ok {
+		b, _ := v.(bool)
+		return b
+	}
+	return def
+}
+
+func (s *framingState) flags(frame map[string]interface{}) (framingFlags, error) {
+	flags := framingFlags{
+		embed: s.opts.Embed,
+		explicit: frameBoolFlag(frame, "@explicit", s.opts.Explicit),
+		requireAll: frameBoolFlag(frame, "@requireAll", s.opts.RequireAll),
+		omitDefault: frameBoolFlag(frame, "@omitDefault", s.opts.OmitDefault),
+	}
+
+	v, ok := frameFlag(frame, "@embed")
+	if !ok {
+		v = flags.embed
+	}
+	switch v {
+	case "", true:
+		flags.embed = "@once"
+	case false:
+		flags.embed = "@never"
+	case "@always", "@once", "@never":
+		flags.embed = v.(string)
+	default:
+		return flags, errorf("invalid @embed value", "%v", v)
+	}
+
+	return flags, nil
+}
+
+func isEmptyMap(v interface{}) bool {
+	m, ok := v.(map[string]interface{})
+	return ok && len(m) == 0
+}
+
+func containsValue(values []interface{}, v interface{}) bool {
+	for _, vv := range values {
+		if reflect.DeepEqual(vv, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func addFrameOutput(parent interface{}, prop string, output interface{}) {
+	switch parent := parent.(type) {
+	case map[string]interface{}:
+		addValue(parent, prop, output, true)
+	case *[]interface{}:
+		*parent = append(*parent, output)
+	}
+}
+
+// frame frames the subjects of a graph, as defined in
+// https://www.w3.org/TR/json-ld11-framing/#framing-algorithm. The framed
+// subjects are added to parent, which is either a node object or a pointer to
+// a list of results.
+func (s *framingState) frame(graph string, embedded bool, subjects []string, frame map[string]interface{}, parent interface{}, prop string) error {
+	flags, err := s.flags(frame)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range s.filterSubjects(graph, subjects, frame, flags.requireAll) {
+		subject := s.graphs[graph][id]
+
+		// Each top-level match is a separate tree
+		if prop == "" {
+			s.uniqueEmbeds = map[string]map[string]bool{graph: {}}
+		} else if s.uniqueEmbeds[graph] == nil {
+			s.uniqueEmbeds[graph] = make(map[string]bool)
+		}
+
+		if !embedded && s.uniqueEmbeds[graph][id] {
+			// Already embedded in another node object
+			continue
+		}
+
+		output := map[string]interface{}{"@id": id}
+		if isBlankNodeID(id) {
+			s.bnodes[id] = append(s.bnodes[id], output)
+		}
+
+		if embedded && (flags.embed == "@never" || s.createsCircularReference(graph, id)) {
+			addFrameOutput(parent, prop, output)
+			continue
+		}
+		if embedded && flags.embed == "@once" && s.uniqueEmbeds[graph][id] {
+			addFrameOutput(parent, prop, output)
+			continue
+		}
+
+		s.uniqueEmbeds[graph][id] = true
+		s.subjectStack = append(s.subjectStack, framedSubject{graph, id})
+
+		if _, ok := s.graphs[id]; ok {
+			recurse := false
+			var subframe map[string]interface{}
+			if graphFrame, ok := frame["@graph"].([]interface{}); ok {
+				recurse = id != "@merged" && id != "@default"
+				if len(graphFrame) > 0 {
+					subframe, _ = graphFrame[0].(map[string]interface{})
+				}
+			} else {
+				recurse = graph != "@merged"
+			}
+			if subframe == nil {
+				subframe = make(map[string]interface{})
+			}
+			if recurse {
+				subjects := sortedNodeIDs(s.graphs[id])
+				if err := s.frame(id, false, subjects, subframe, output, "@graph"); err != nil {
+					return err
+				}
+			}
+		}
+
+		for _, k := range sortedKeys(subject) {
+			if isKeyword(k) {
+				output[k] = subject[k]
+				if k == "@type" {
+					for _, t := range toArray(subject[k]) {
+						if t, ok := t.(string); ok && isBlankNodeID(t) {
+							s.bnodes[t] = append(s.bnodes[t], nil)
+						}
+					}
+				}
+				continue
+			}
+
+			propFrame, inFrame := frame[k].([]interface{})
+			if flags.explicit && !inFrame {
+				continue
+			}
+
+			var subframe map[string]interface{}
+			if inFrame && len(propFrame) > 0 {
+				subframe, _ = propFrame[0].(map[string]interface{})
+			}
+			if subframe == nil {
+				subframe = s.implicitFrame(flags)
+			}
+
+			for _, o := range toArray(subject[k]) {
+				om, _ := o.(map[string]interface{})
+				if l, ok := om["@list"]; ok {
+					listFrame := s.implicitFrame(flags)
+					if lf, ok := subframe["@list"].([]interface{}); ok && len(lf) > 0 {
+						if lf, ok := lf[0].(map[string]interface{}); ok {
+							listFrame = lf
+						}
+					}
+
+					list := map[string]interface{}{"@list": []interface{}{}}
+					addFrameOutput(output, k, list)
+					for _, item := range toArray(l) {
+						if ref, ok := item.(map[string]interface{}); ok && isNodeReference(ref) {
+							refID, _ := ref["@id"].(string)
+							if err := s.frame(graph, true, []string{refID}, listFrame, list, "@list"); err != nil {
+								return err
+							}
+						} else {
+							addFrameOutput(list, "@list", item)
+						}
+					}
+				} else if isNodeReference(om) {
+					refID, _ := om["@id"].(string)
+					if err := s.frame(graph, true, []string{refID}, subframe, output, k); err != nil {
+						return err
+					}
+				} else if valueMatch(subframe, om) {
+					addFrameOutput(output, k, o)
+				}
+			}
+		}
+
+		// Handle default values
+		for _, k := range sortedKeys(frame) {
+			next := map[string]interface{}{}
+			if values := toArray(frame[k]); len(values) > 0 {
+				if m, ok := values[0].(map[string]interface{}); ok {
+					next = m
+				}
+			}
+
+			if k == "@type" {
+				def, ok := next["@default"]
+				if !ok {
+					continue
+				}
+				if _, ok := output[k]; !ok {
+					output[k] = []interface{}{def}
+				}
+				continue
+			} else if isKeyword(k) {
+				continue
+			}
+
+			if frameBoolFlag(next, "@omitDefault", flags.omitDefault) {
+				continue
+			}
+			if _, ok := output[k]; ok {
+				continue
+			}
+
+			preserve := []interface{}{"@null"}
+			if def, ok := next["@default"]; ok {
+				preserve = toArray(def)
+			}
+			output[k] = []interface{}{map[string]interface{}{"@preserve": preserve}}
+		}
+
+		addFrameOutput(parent, prop, output)
+		s.subjectStack = s.subjectStack[:len(s.subjectStack)-1]
+	}
+
+	return nil
+}
+
+func (s *framingState) implicitFrame(flags framingFlags) map[string]interface{} {
+	return map[string]interface{}{
+		"@embed": []interface{}{flags.embed},
+		"@explicit": []interface{}{flags.explicit},
+		"@requireAll": []interface{}{flags.requireAll},
+		"@omitDefault": []interface{}{flags.omitDefault},
+	}
+}
+
+func (s *framingState) createsCircularReference(graph, id string) bool {
+	for i := len(s.subjectStack) - 1; i >= 0; i-- {
+		subject := s.subjectStack[i]
+		if subject.graph == graph && subject.id == id {
+			return true
+		}
+	}
+	return false
+}
+
+// filterSubjects returns the sorted list of subjects matching the frame.
+func (s *framingState) filterSubjects(graph string, subjects []string, frame map[string]interface{}, requireAll bool) []string {
+	var matches []string
+	for _, id := range subjects {
+		node, ok := s.graphs[graph][id]
+		if !ok {
+			continue
+		}
+		if s.filterSubject(graph, node, frame, requireAll) {
+			matches = append(matches, id)
+		}
+	}
+	sort.Strings(matches)
+	return matches
+}
+
+// filterSubject checks whether a node matches a frame, as defined in
+// https://www.w3.org/TR/json-ld11-framing/#frame-matching.
+func (s *framingState) filterSubject(graph string, node, frame map[string]interface{}, requireAll bool) bool {
+	wildcard := true
+	matchesSome := false
+
+	for _, k := range sortedKeys(frame) {
+		matchThis := false
+		nodeValues := toArray(node[k])
+		frameValues := toArray(frame[k])
+		isEmpty := len(frameValues) == 0
+
+		switch {
+		case k == "@id":
+			if len(frameValues) == 0 || isEmptyMap(frameValues[0]) {
+				matchThis = true
+			} else if len(nodeValues) > 0 {
+				matchThis = containsValue(frameValues, nodeValues[0])
+			}
+			if !requireAll {
+				return matchThis
+			}
+		case k == "@type":
+			wildcard = false
+			if isEmpty {
+				if len(nodeValues) > 0 {
+					return false
+				}
+				matchThis = true
+			} else if len(frameValues) == 1 && isEmptyMap(frameValues[0]) {
+				matchThis = len(nodeValues) > 0
+			} else {
+				for _, t := range frameValues {
+					if m, ok := t.(map[string]interface{}); ok {
+						if _, ok := m["@default"]; ok {
+							matchThis = true
+						}
+					} else if containsValue(nodeValues, t) {
+						matchThis = true
+					}
+				}
+				if !requireAll {
+					return matchThis
+				}
+			}
+		case isKeyword(k):
+			continue
+		default:
+			var propFrame map[string]interface{}
+			if !isEmpty {
+				propFrame, _ = frameValues[0].(map[string]interface{})
+			}
+			_, hasDefault := propFrame["@default"]
+
+			wildcard = false
+
+			if len(nodeValues) == 0 && hasDefault {
+				continue
+			}
+			if len(nodeValues) > 0 && isEmpty {
+				return false
+			}
+
+			if propFrame == nil {
+				if len(nodeValues) > 0 {
+					return false
+				}
+				matchThis = true
+			} else if l, ok := propFrame["@list"]; ok {
+				listFrame, _ := firstMap(toArray(l))
+				if len(nodeValues) > 0 {
+					nodeList, _ := nodeValues[0].(map[string]interface{})
+					for _, item := range toArray(nodeList["@list"]) {
+						if isValueObject(listFrame) {
+							matchThis = matchThis || valueMatch(listFrame, item.(map[string]interface{}))
+						} else if listFrame != nil {
+							matchThis = matchThis || s.nodeMatch(graph, listFrame, item, requireAll)
+						}
+					}
+				}
+			} else if isValueObject(propFrame) {
+				for _, v := range nodeValues {
+					if m, ok := v.(map[string]interface{}); ok && valueMatch(propFrame, m) {
+						matchThis = true
+					}
+				}
+			} else if _, ok := propFrame["@id"]; ok && isNodeReference(propFrame) {
+				for _, v := range nodeValues {
+					if s.nodeMatch(graph, propFrame, v, requireAll) {
+						matchThis = true
+					}
+				}
+			} else {
+				matchThis = len(nodeValues) > 0
+			}
+		}
+
+		if !matchThis && requireAll {
+			return false
+		}
+		matchesSome = matchesSome || matchThis
+	}
+
+	return wildcard || matchesSome
+}
+
+func firstMap(values []interface{}) (map[string]interface{}, bool) {
+	if len(values) == 0 {
+		return nil, false
+	}
+	m, ok := values[0].(map[string]interface{})
+	return m, ok
+}
+
+func (s *framingState) nodeMatch(graph string, frame map[string]interface{}, v interface{}, requireAll bool) bool {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	id, ok := m["@id"].(string)
+	if !ok {
+		return false
+	}
+	node, ok := s.graphs[graph][id]
+	if !ok {
+		return false
+	}
+	return s.filterSubject(graph, node, frame, requireAll)
+}
+
+// valueMatch checks whether a value object matches a value pattern, as
+// defined in https://www.w3.org/TR/json-ld11-framing/#value-matching.
+func valueMatch(pattern, value map[string]interface{}) bool {
+	values := toArray(pattern["@value"])
+	types := toArray(pattern["@type"])
+	langs := toArray(pattern["@language"])
+	if len(values) == 0 && len(types) == 0 && len(langs) == 0 {
+		return true
+	}
+
+	v, hasValue := value["@value"]
+	t, hasType := value["@type"]
+	lang, hasLang := value["@language"]
+
+	matches := func(patterns []interface{}, v interface{}, ok bool) bool {
+		if len(patterns) == 0 {
+			return !ok
+		}
+		return containsValue(patterns, v) || (ok && isEmptyMap(patterns[0]))
+	}
+
+	if len(values) > 0 && !matches(values, v, hasValue) {
+		return false
+	}
+	return matches(types, t, hasType) && matches(langs, lang, hasLang)
+}
+
+// pruneBlankNodeIDs removes the identifiers of blank nodes which are only
+// referenced once.
+func (s *framingState) pruneBlankNodeIDs() {
+	for _, outputs := range s.bnodes {
+		if len(outputs) != 1 {
+			continue
+		}
+		if id, _ := outputs[0]["@id"].(string); isBlankNodeID(id) {
+			delete(outputs[0], "@id")
+		}
+	}
+}
+
+// removePreserve replaces @preserve entries with their value and "@null" with
+// null.
+func removePreserve(v interface{}) interface{} {
+	switch v := v.(type) {
+	case []interface{}:
+		result := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			result = append(result, removePreserve(item))
+		}
+		return result
+	case map[string]interface{}:
+		if preserved, ok := v["@preserve"]; ok {
+			return removePreserve(preserved)
+		}
+		for k, vv := range v {
+			v[k] = removePreserve(vv)
+		}
+		return v
+	case string:
+		if v == "@null" {
+			return nil
+		}
+		return v
+	default:
+		return v
+	}
+}
@@ -0,0 +1,226 @@
+package jsonld
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+const libraryInput = `{
+	"@context": {
+		"dc11": "http://purl.org/dc/elements/1.1/",
+		"ex": "http://example.org/vocab#",
+		"xsd": "http://www.w3.org/2001/XMLSchema#",
+		"ex:contains": {"@type": "@id"}
+	},
+	"@graph": [
+		{
+			"@id": "http://example.org/library",
+			"@type": "ex:Library",
+			"ex:contains": "http://example.org/library/the-republic"
+		},
+		{
+			"@id": "http://example.org/library/the-republic",
+			"@type": "ex:Book",
+			"dc11:creator": "Plato",
+			"dc11:title": "The Republic",
+			"ex:contains": "http://example.org/library/the-republic#introduction"
+		},
+		{
+			"@id": "http://example.org/library/the-republic#introduction",
+			"@type": "ex:Chapter",
+			"dc11:description": "An introductory chapter on The Republic.",
+			"dc11:title": "The Introduction"
+		}
+	]
+}`
+
+var frameTests = []struct{
+	name string
+	in string
+	frame string
+	out string
+}{
+	{
+		name: "library",
+		in: libraryInput,
+		frame: `{
+			"@context": {
+				"dc11": "http://purl.org/dc/elements/1.1/",
+				"ex": "http://example.org/vocab#"
+			},
+			"@type": "ex:Library",
+			"ex:contains": {
+				"@type": "ex:Book",
+				"ex:contains": {"@type": "ex:Chapter"}
+			}
+		}`,
+		out: `{
+			"@context": {
+				"dc11": "http://purl.org/dc/elements/1.1/",
+				"ex": "http://example.org/vocab#"
+			},
+			"@id": "http://example.org/library",
+			"@type": "ex:Library",
+			"ex:contains": {
+				"@id": "http://example.org/library/the-republic",
+				"@type": "ex:Book",
+				"dc11:creator": "Plato",
+				"dc11:title": "The Republic",
+				"ex:contains": {
+					"@id": "http://example.org/library/the-republic#introduction",
+					"@type": "ex:Chapter",
+					"dc11:description": "An introductory chapter on The Republic.",
+					"dc11:title": "The Introduction"
+				}
+			}
+		}`,
+	},
+	{
+		name: "explicit, default and never embed",
+		in: libraryInput,
+		frame: `{
+			"@context": {
+				"dc11": "http://purl.org/dc/elements/1.1/",
+				"ex": "http://example.org/vocab#"
+			},
+			"@type": "ex:Book",
+			"@explicit": true,
+			"dc11:title": {},
+			"dc11:publisher": {"@default": "Unknown"},
+			"ex:rating": {},
+			"ex:contains": {"@embed": "@never"}
+		}`,
+		out: `{
+			"@context": {
+				"dc11": "http://purl.org/dc/elements/1.1/",
+				"ex": "http://example.org/vocab#"
+			},
+			"@id": "http://example.org/library/the-republic",
+			"@type": "ex:Book",
+			"dc11:title": "The Republic",
+			"dc11:publisher": "Unknown",
+			"ex:rating": null,
+			"ex:contains": {"@id": "http://example.org/library/the-republic#introduction"}
+		}`,
+	},
+	{
+		name: "require all",
+		in: libraryInput,
+		frame: `{
+			"@context": {"dc11": "http://purl.org/dc/elements/1.1/"},
+			"@requireAll": true,
+			"dc11:title": {},
+			"dc11:description": {}
+		}`,
+		out: `{
+			"@context": {"dc11": "http://purl.org/dc/elements/1.1/"},
+			"@id": "http://example.org/library/the-republic#introduction",
+			"@type": "http://example.org/vocab#Chapter",
+			"dc11:description": "An introductory chapter on The Republic.",
+			"dc11:title": "The Introduction"
+		}`,
+	},
+	{
+		name: "blank node type",
+		in: `{
+			"@context": {"@vocab": "http://example.org/"},
+			"@id": "http://example.org/a",
+			"@type": "_:t",
+			"name": "A"
+		}`,
+		frame: `{"@context": {"@vocab": "http://example.org/"}}`,
+		out: `{
+			"@context": {"@vocab": "http://example.org/"},
+			"@id": "http://example.org/a",
+			"@type": "_:b0",
+			"name": "A"
+		}`,
+	},
+	{
+		name: "blank node embedded in a named graph",
+		in: `{
+			"@context": {"@vocab": "http://example.org/"},
+			"@id": "http://example.org/g",
+			"@graph": [
+				{"@id": "_:a", "p": {"@id": "_:b"}},
+				{"@id": "_:b", "q": "x"}
+			]
+		}`,
+		frame: `{
+			"@context": {"@vocab": "http://example.org/"},
+			"@id": "http://example.org/g",
+			"@graph": {}
+		}`,
+		out: `{
+			"@context": {"@vocab": "http://example.org/"},
+			"@id": "http://example.org/g",
+			"@graph": [{"p": {"q": "x"}}]
+		}`,
+	},
+}
+
+func TestFrame(t *testing.T) {
+	for _, test := range frameTests {
+		var in, frame, want interface{}
+		if err := json.Unmarshal([]byte(test.in), &in); err != nil {
+			t.Fatalf("%v: json.Unmarshal(in) = %v", test.name, err)
+		}
+		if err := json.Unmarshal([]byte(test.frame), &frame); err != nil {
+			t.Fatalf("%v: json.Unmarshal(frame) = %v", test.name, err)
+		}
+		if err := json.Unmarshal([]byte(test.out), &want); err != nil {
+			t.Fatalf("%v: json.Unmarshal(out) = %v", test.name, err)
+		}
+
+		out, err := Frame(in, frame, nil)
+		if err != nil {
+			t.Errorf("%v: Frame() = %v", test.name, err)
+			continue
+		}
+
+		got := roundTripJSON(t, out)
+		if !reflect.DeepEqual(got, want) {
+			b, _ := json.Marshal(out)
+			t.Errorf("%v: Frame() = %v, want %v", test.name, string(b), test.out)
+		}
+	}
+}
+
+func TestFrame_base(t *testing.T) {
+	var in, frame, want interface{}
+	err := json.Unmarshal([]byte(`{
+		"@context": {"@vocab": "http://schema.org/"},
+		"@id": "http://example.org/people/alice",
+		"name": "Alice",
+		"knows": {"@id": "http://example.org/people/bob"}
+	}`), &in)
+	if err != nil {
+		t.Fatalf("json.Unmarshal(in) = %v", err)
+	}
+	err = json.Unmarshal([]byte(`{
+		"@context": {"@vocab": "http://schema.org/", "knows": {"@type": "@id"}},
+		"@id": "alice"
+	}`), &frame)
+	if err != nil {
+		t.Fatalf("json.Unmarshal(frame) = %v", err)
+	}
+	err = json.Unmarshal([]byte(`{
+		"@context": {"@vocab": "http://schema.org/", "knows": {"@type": "@id"}},
+		"@id": "alice",
+		"name": "Alice",
+		"knows": "bob"
+	}`), &want)
+	if err != nil {
+		t.Fatalf("json.Unmarshal(out) = %v", err)
+	}
+
+	out, err := Frame(in, frame, &Options{Base: "http://example.org/people/"})
+	if err != nil {
+		t.Fatalf("Frame() = %v", err)
+	}
+	if got := roundTripJSON(t, out); !reflect.DeepEqual(got, want) {
+		b, _ := json.Marshal(out)
+		t.Errorf("Frame() = %v, want %v", string(b), want)
+	}
+}
@@ -0,0 +1,228 @@
+package jsonld
+
+import (
+	"regexp"
+	"strings"
+)
+
+// iriRegexp splits an IRI reference into its components, as defined in
+// RFC 3986 appendix B.
+var iriRegexp = regexp.MustCompile(`^(([^:/?#]+):)?(//([^/?#]*))?([^?#]*)(\?([^#]*))?(#(.*))?$`)
+
+var schemeRegexp = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*:`)
+
+// IsAbsoluteIRI checks whether s is an absolute IRI, ie. whether it starts
+// with a scheme.
+func IsAbsoluteIRI(s string) bool {
+	return schemeRegexp.MatchString(s)
+}
+
+// iriRef is an IRI reference split into its components. Undefined components
+// are distinguished from empty ones.
+type iriRef struct {
+	scheme, authority, path, query, fragment string
+	hasScheme, hasAuthority, hasQuery, hasFragment bool
+}
+
+func parseIRIRef(s string) *iriRef {
+	m := iriRegexp.FindStringSubmatch(s)
+	if m == nil {
+		// Can't happen, every string matches
+		return &iriRef{path: s}
+	}
+	return &iriRef{
+		scheme: m[2],
+		hasScheme: m[1] != "",
+		authority: m[4],
+		hasAuthority: m[3] != "",
+		path: m[5],
+		query: m[7],
+		hasQuery: m[6] != "",
+		fragment: m[9],
+		hasFragment: m[8] != "",
+	}
+}
+
+func (ref *iriRef) String() string {
+	var sb strings.Builder
+	if ref.hasScheme {
+		sb.WriteString(ref.scheme)
+		sb.WriteByte(':')
+	}
+	if ref.hasAuthority {
+		sb.WriteString("//")
+		sb.WriteString(ref.authority)
+	}
+	sb.WriteString(ref.path)
+	if ref.hasQuery {
+		sb.WriteByte('?')
+		sb.WriteString(ref.query)
+	}
+	if ref.hasFragment {
+		sb.WriteByte('#')
+		sb.WriteString(ref.fragment)
+	}
+	return sb.String()
+}
+
+// ResolveIRI resolves an IRI reference against a base IRI, as defined in
+// RFC 3986 section 5.2. If base is empty, the reference is returned as-is.
+func ResolveIRI(base, ref string) string {
+	if base == "" {
+		return ref
+	}
+
+	r := parseIRIRef(ref)
+	if r.hasScheme {
+		r.path = removeDotSegments(r.path)
+		return r.String()
+	}
+
+	b := parseIRIRef(base)
+	t := &iriRef{
+		scheme: b.scheme,
+		hasScheme: b.hasScheme,
+		fragment: r.fragment,
+		hasFragment: r.hasFragment,
+	}
+	switch {
+	case r.hasAuthority:
+		t.authority, t.hasAuthority = r.authority, true
+		t.path = removeDotSegments(r.path)
+		t.query, t.hasQuery = r.query, r.hasQuery
+	case r.path == "":
+		t.authority, t.hasAuthority = b.authority, b.hasAuthority
+		t.path = b.path
+		if r.hasQuery {
+			t.query, t.hasQuery = r.query, true
+		} else {
+			t.query, t.hasQuery = b.query, b.hasQuery
+		}
+	default:
+		t.authority, t.hasAuthority = b.authority, b.hasAuthority
+		if strings.HasPrefix(r.path, "/") {
+			t.path = removeDotSegments(r.path)
+		} else {
+			t.path = removeDotSegments(mergePaths(b, r.path))
+		}
+		t.query, t.hasQuery = r.query, r.hasQuery
+	}
+	return t.String()
+}
+
+// mergePaths merges a relative path with the path of a base IRI, as defined
+// in RFC 3986 section 5.2.3.
+func mergePaths(base *iriRef, path string) string {
+	if base.hasAuthority && base.path == "" {
+		return "/" + path
+	}
+	i := strings.LastIndexByte(base.path, '/')
+	return base.path[:i+1] + path
+}
+
+// removeDotSegments removes the "." and ".." segments of a path, as defined
+// in RFC 3986 section 5.2.4.
+func removeDotSegments(path string) string {
+	if !strings.Contains(path, ".") {
+		return path
+	}
+
+	var out []string
+	for path != "" {
+		switch {
+		case strings.HasPrefix(path, "../"):
+			path = path[3:]
+		case strings.HasPrefix(path, "./"):
+			path = path[2:]
+		case strings.HasPrefix(path, "/./"):
+			path = path[2:]
+		case path == "/.":
+			path = "/"
+		case strings.HasPrefix(path, "/../"):
+			path = path[3:]
+			if len(out) > 0 {
+				out = out[:len(out)-1]
+			}
+		case path == "/..":
+			path = "/"
+			if len(out) > 0 {
+				out = out[:len(out)-1]
+			}
+		case path == "." || path == "..":
+			path = ""
+		default:
+			i := strings.IndexByte(path[1:], '/')
+			if i < 0 {
+				out = append(out, path)
+				path = ""
+			} else {
+				out = append(out, path[:i+1])
+				path = path[i+1:]
+			}
+		}
+	}
+	return strings.Join(out, "")
+}
+
+// RelativeIRI returns an IRI reference which resolves to iri against base. If
+// iri can't be expressed relative to base, it is returned as-is.
+func RelativeIRI(base, iri string) string {
+	if base == "" {
+		return iri
+	}
+
+	b, r := parseIRIRef(base), parseIRIRef(iri)
+	if !b.hasScheme || !r.hasScheme || b.scheme != r.scheme || b.hasAuthority != r.hasAuthority || b.authority != r.authority {
+		return iri
+	}
+
+	var rel string
+	if r.path != b.path || (!r.hasQuery && b.hasQuery) {
+		rel = relativePath(b.path, r.path)
+	}
+	if r.hasQuery && (rel != "" || r.query != b.query || !b.hasQuery) {
+		rel += "?" + r.query
+	}
+	if r.hasFragment {
+		rel += "#" + r.fragment
+	}
+
+	// Make sure the result resolves to the original IRI
+	if ResolveIRI(base, rel) != iri {
+		return iri
+	}
+	return rel
+}
+
+// relativePath returns a relative path which resolves to path against the
+// directory of base.
+func relativePath(base, path string) string {
+	if !strings.HasPrefix(path, "/") {
+		return path
+	}
+
+	dir := base[:strings.LastIndexByte(base, '/')+1]
+	if dir == "" {
+		dir = "/"
+	}
+	baseSegments := strings.Split(dir, "/")
+	segments := strings.Split(path, "/")
+
+	// Skip the common directories
+	i := 0
+	for i < len(baseSegments)-1 && i < len(segments)-1 && baseSegments[i] == segments[i] {
+		i++
+	}
+
+	var sb strings.Builder
+	for j := i; j < len(baseSegments)-1; j++ {
+		sb.WriteString("../")
+	}
+	rest := strings.Join(segments[i:], "/")
+	if sb.Len() == 0 && (rest == "" || strings.Contains(strings.SplitN(rest, "/", 2)[0], ":")) {
+		// Avoid empty references and first segments which look like a scheme
+		sb.WriteString("./")
+	}
+	sb.WriteString(rest)
+	return sb.String()
+}
@@ -0,0 +1,108 @@
+package jsonld
+
+import (
+	"testing"
+)
+
+// Examples from RFC 3986 section 5.4
+const rfc3986Base = "http://a/b/c/d;p?q"
+
+var resolveIRITests = map[string]string{
+	"g:h": "g:h",
+	"g": "http://a/b/c/g",
+	"./g": "http://a/b/c/g",
+	"g/": "http://a/b/c/g/",
+	"/g": "http://a/g",
+	"//g": "http://g",
+	"?y": "http://a/b/c/d;p?y",
+	"g?y": "http://a/b/c/g?y",
+	"#s": "http://a/b/c/d;p?q#s",
+	"g#s": "http://a/b/c/g#s",
+	"g?y#s": "http://a/b/c/g?y#s",
+	";x": "http://a/b/c/;x",
+	"g;x": "http://a/b/c/g;x",
+	"g;x?y#s": "http://a/b/c/g;x?y#s",
+	"": "http://a/b/c/d;p?q",
+	".": "http://a/b/c/",
+	"./": "http://a/b/c/",
+	"..": "http://a/b/",
+	"../": "http://a/b/",
+	"../g": "http://a/b/g",
+	"../..": "http://a/",
+	"../../": "http://a/",
+	"../../g": "http://a/g",
+	"../../../g": "http://a/g",
+	"../../../../g": "http://a/g",
+	"/./g": "http://a/g",
+	"/../g": "http://a/g",
+	"g.": "http://a/b/c/g.",
+	".g": "http://a/b/c/.g",
+	"g..": "http://a/b/c/g..",
+	"..g": "http://a/b/c/..g",
+	"./../g": "http://a/b/g",
+	"./g/.": "http://a/b/c/g/",
+	"g/./h": "http://a/b/c/g/h",
+	"g/../h": "http://a/b/c/h",
+	"g;x=1/./y": "http://a/b/c/g;x=1/y",
+	"g;x=1/../y": "http://a/b/c/y",
+	"g?y/./x": "http://a/b/c/g?y/./x",
+	"g?y/../x": "http://a/b/c/g?y/../x",
+	"g#s/./x": "http://a/b/c/g#s/./x",
+	"g#s/../x": "http://a/b/c/g#s/../x",
+	"http:g": "http:g",
+}
+
+func TestIsAbsoluteIRI(t *testing.T) {
+	tests := map[string]bool{
+		"http://example.org/": true,
+		"urn:isbn:123": true,
+		"g:h": true,
+		"a+b-c.d:x": true,
+		"_:b0": false,
+		"1a:b": false,
+		"relative/path": false,
+		"./g:h": false,
+		":x": false,
+		"": false,
+	}
+	for s, want := range tests {
+		if got := IsAbsoluteIRI(s); got != want {
+			t.Errorf("IsAbsoluteIRI(%q) = %v, want %v", s, got, want)
+		}
+	}
+}
+
+func TestResolveIRI(t *testing.T) {
+	for ref, want := range resolveIRITests {
+		if got := ResolveIRI(rfc3986Base, ref); got != want {
+			t.Errorf("ResolveIRI(%q, %q) = %q, want %q", rfc3986Base, ref, got, want)
+		}
+	}
+}
+
+func TestRelativeIRI(t *testing.T) {
+	tests := map[string]string{
+		"http://a/b/c/g": "g",
+		"http://a/b/c/g/": "g/",
+		"http://a/b/c/": "./",
+		"http://a/b/g": "../g",
+		"http://a/g": "../../g",
+		"http://a/b/c/d;p?y": "?y",
+		"http://a/b/c/d;p?q#s": "#s",
+		"http://a/b/c/g?y#s": "g?y#s",
+		"http://a/b/c/d;p": "d;p",
+		"http://a/b/c/g:h": "./g:h",
+		"http://g/": "http://g/",
+		"https://a/b/c/g": "https://a/b/c/g",
+		"urn:isbn:123": "urn:isbn:123",
+	}
+	for iri, want := range tests {
+		got := RelativeIRI(rfc3986Base, iri)
+		if got != want {
+			t.Errorf("RelativeIRI(%q, %q) = %q, want %q", rfc3986Base, iri, got, want)
+		}
+		if resolved := ResolveIRI(rfc3986Base, got); resolved != iri {
+			t.Errorf("ResolveIRI(%q, %q) = %q, want %q", rfc3986Base, got, resolved, iri)
+		}
+	}
+}
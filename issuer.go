@@ -0,0 +1,62 @@
+package jsonld
+
+import (
+	"strconv"
+)
+
+// IdentifierIssuer issues new blank node identifiers, as defined in
+// https://www.w3.org/TR/json-ld11-api/#generate-blank-node-identifier.
+type IdentifierIssuer struct {
+	prefix string
+	counter int
+	issued map[string]string
+	order []string
+}
+
+// NewIdentifierIssuer creates a new identifier issuer. Issued identifiers are
+// made of prefix followed by a counter, e.g. "_:b0" for the prefix "_:b".
+func NewIdentifierIssuer(prefix string) *IdentifierIssuer {
+	return &IdentifierIssuer{prefix: prefix, issued: make(map[string]string)}
+}
+
+// Issue returns the new identifier for old. If old is empty, a new identifier
+// is always returned.
+func (iss *IdentifierIssuer) Issue(old string) string {
+	if id, ok := iss.issued[old]; ok && old != "" {
+		return id
+	}
+
+	id := iss.prefix + strconv.Itoa(iss.counter)
+	iss.counter++
+	if old != "" {
+		iss.issued[old] = id
+		iss.order = append(iss.order, old)
+	}
+	return id
+}
+
+// Issued returns the identifier previously issued for old, if any.
+func (iss *IdentifierIssuer) Issued(old string) (id string, ok bool) {
+	id, ok = iss.issued[old]
+	return id, ok
+}
+
+// Identifiers returns the old identifiers, in the order in which new
+// identifiers have been issued for them.
+func (iss *IdentifierIssuer) Identifiers() []string {
+	return append([]string(nil), iss.order...)
+}
+
+// Clone returns a copy of the issuer.
+func (iss *IdentifierIssuer) Clone() *IdentifierIssuer {
+	c := &IdentifierIssuer{
+		prefix: iss.prefix,
+		counter: iss.counter,
+		issued: make(map[string]string, len(iss.issued)),
+		order: append([]string(nil), iss.order...),
+	}
+	for k, v := range iss.issued {
+		c.issued[k] = v
+	}
+	return c
+}
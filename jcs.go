@@ -0,0 +1,148 @@
+package jsonld
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// canonicalJSON serializes a JSON value with the JSON Canonicalization Scheme,
+// as defined in RFC 8785. v must be a value produced by the encoding/json
+// package.
+func canonicalJSON(v interface{}) (string, error) {
+	var sb strings.Builder
+	if err := writeCanonicalJSON(&sb, v); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+func writeCanonicalJSON(sb *strings.Builder, v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		sb.WriteString("null")
+	case bool:
+		sb.WriteString(strconv.FormatBool(v))
+	case float64:
+		s, err := formatJSONNumber(v)
+		if err != nil {
+			return err
+		}
+		sb.WriteString(s)
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return fmt.Errorf("jsonld: invalid JSON number %q", v)
+		}
+		return writeCanonicalJSON(sb, f)
+	case int:
+		sb.WriteString(strconv.Itoa(v))
+	case int64:
+		sb.WriteString(strconv.FormatInt(v, 10))
+	case string:
+		writeCanonicalString(sb, v)
+	case []interface{}:
+		sb.WriteByte('[')
+		for i, item := range v {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			if err := writeCanonicalJSON(sb, item); err != nil {
+				return err
+			}
+		}
+		sb.WriteByte(']')
+	case map[string]interface{}:
+		// Keys are sorted by their UTF-16 code units
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			return lessUTF16(keys[i], keys[j])
+		})
+
+		sb.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			writeCanonicalString(sb, k)
+			sb.WriteByte(':')
+			if err := writeCanonicalJSON(sb, v[k]); err != nil {
+				return err
+			}
+		}
+		sb.WriteByte('}')
+	default:
+		return fmt.Errorf("jsonld: cannot serialize %T as JSON", v)
+	}
+	return nil
+}
+
+func lessUTF16(a, b string) bool {
+	ua, ub := utf16.Encode([]rune(a)), utf16.Encode([]rune(b))
+	for i := 0; i < len(ua) && i < len(ub); i++ {
+		if ua[i] != ub[i] {
+			return ua[i] < ub[i]
+		}
+	}
+	return len(ua) < len(ub)
+}
+
+func writeCanonicalString(sb *strings.Builder, s string) {
+	sb.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			sb.WriteString(`\"`)
+		case '\\':
+			sb.WriteString(`\\`)
+		case '\b':
+			sb.WriteString(`\b`)
+		case '\f':
+			sb.WriteString(`\f`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\t':
+			sb.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(sb, `\u%04x`, r)
+			} else {
+				sb.WriteRune(r)
+			}
+		}
+	}
+	sb.WriteByte('"')
+}
+
+// formatJSONNumber formats a number like the ECMAScript Number.toString
+// method.
+func formatJSONNumber(f float64) (string, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return "", fmt.Errorf("jsonld: cannot serialize %v as JSON", f)
+	}
+	if f == 0 {
+		// Also handles negative zero
+		return "0", nil
+	}
+
+	abs := math.Abs(f)
+	if abs >= 1e-6 && abs < 1e21 {
+		return strconv.FormatFloat(f, 'f', -1, 64), nil
+	}
+
+	s := strconv.FormatFloat(f, 'e', -1, 64)
+	i := strings.IndexByte(s, 'e')
+	mantissa, exp := s[:i], s[i+1:]
+	sign := exp[:1]
+	exp = strings.TrimLeft(exp[1:], "0")
+	return mantissa + "e" + sign + exp, nil
+}
@@ -0,0 +1,27 @@
+package jsonld
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestCanonicalJSON(t *testing.T) {
+	tests := map[string]string{
+		`{"b": 2, "a": 1}`: `{"a":1,"b":2}`,
+		`[1e21, 1e-7, 0.000001, -0, 123456789012345680000, 4.5]`: `[1e+21,1e-7,0.000001,0,123456789012345680000,4.5]`,
+		`"\u0001\n\"é€<>"`: `"\u0001\n\"é€<>"`,
+		`{"\u20ac": 1, "\r": 2, "\ud83d\ude00": 3, "\ufb33": 4}`: "{\"\\r\":2,\"\u20ac\":1,\"\U0001f600\":3,\"\ufb33\":4}",
+		`[null, true, false, {}]`: `[null,true,false,{}]`,
+	}
+	for in, want := range tests {
+		var v interface{}
+		if err := json.Unmarshal([]byte(in), &v); err != nil {
+			t.Fatalf("json.Unmarshal(%v) = %v", in, err)
+		}
+		if got, err := canonicalJSON(v); err != nil {
+			t.Errorf("canonicalJSON(%v) = %v", in, err)
+		} else if got != want {
+			t.Errorf("canonicalJSON(%v) = %v, want %v", in, got, want)
+		}
+	}
+}
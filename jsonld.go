@@ -4,6 +4,7 @@ package jsonld
 
 import (
 	"bytes"
+	"fmt"
 )
 
 const (
@@ -25,6 +26,126 @@ type Type struct {
 	URI string
 }
 
+// Options contains options for the JSON-LD processing algorithms.
+type Options struct {
+	// Base is the base IRI of the document, usually the URL it has been
+	// loaded from. It's used to resolve relative IRIs and references to
+	// remote contexts, unless overridden by @base.
+	Base string
+	// ExpandContext, if non-nil, will be applied to the input document before
+	// its own context when expanding.
+	ExpandContext *Context
+	// FetchContext, if non-nil, will be called to fetch remote contexts. By
+	// default, remote contexts are not fetched.
+	FetchContext FetchContextFunc
+	// FetchDocument, if non-nil, will be called to fetch the contexts
+	// referenced by @import, which are merged as JSON before being processed.
+	// By default, they are not fetched.
+	FetchDocument FetchDocumentFunc
+	// KeepArrays, if set, prevents arrays with a single element from being
+	// replaced with their element when compacting.
+	KeepArrays bool
+	// KeepAbsoluteIRIs, if set, prevents IRIs from being converted to
+	// relative IRI references against the base IRI when compacting.
+	KeepAbsoluteIRIs bool
+	// ProcessingMode is either "json-ld-1.1" (the default) or "json-ld-1.0".
+	// In JSON-LD 1.0 mode, features introduced in JSON-LD 1.1 are rejected
+	// or ignored, as defined in the specification.
+	ProcessingMode string
+
+	// Embed is the default value of the @embed framing flag: "@once" (the
+	// default), "@always" or "@never".
+	Embed string
+	// Explicit is the default value of the @explicit framing flag.
+	Explicit bool
+	// RequireAll is the default value of the @requireAll framing flag.
+	RequireAll bool
+	// OmitDefault is the default value of the @omitDefault framing flag.
+	OmitDefault bool
+
+	// ProduceGeneralizedRDF, if set, allows blank nodes to be used as
+	// predicates when converting to RDF.
+	ProduceGeneralizedRDF bool
+	// UseNativeTypes, if set, converts xsd:boolean, xsd:integer and
+	// xsd:double literals to native JSON values when converting from RDF.
+	UseNativeTypes bool
+	// UseRDFType, if set, keeps rdf:type statements as regular properties
+	// instead of converting them to @type when converting from RDF.
+	UseRDFType bool
+	// RDFDirection selects how the base direction of strings is represented
+	// in RDF: "i18n-datatype", "compound-literal", or empty to drop it.
+	RDFDirection string
+}
+
+// Error is a JSON-LD processing error. Code is one of the error codes defined
+// in https://www.w3.org/TR/json-ld11-api/#jsonlderrorcode, for instance
+// "invalid IRI mapping".
+type Error struct {
+	Code string
+	Message string
+}
+
+func (err *Error) Error() string {
+	if err.Message == "" {
+		return "jsonld: " + err.Code
+	}
+	return "jsonld: " + err.Code + ": " + err.Message
+}
+
+func errorf(code string, format string, v ...interface{}) error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, v...)}
+}
+
+// processor holds the state of a JSON-LD processing algorithm.
+type processor struct {
+	opts *Options
+	contexts map[string]*Context // Remote contexts cache
+	inverses map[*Context]inverseContext
+	scopedContexts map[scopedContextKey]*Context
+
+	// keepUnmapped keeps properties which don't expand to an IRI instead of
+	// dropping them, for the Decoder.
+	keepUnmapped bool
+	// frameExpansion enables the special expansion rules for frames.
+	frameExpansion bool
+	// compactErr is the first error found by compactIRI, which doesn't return
+	// errors itself.
+	compactErr error
+}
+
+// isJSONLD10 checks whether the processing mode is JSON-LD 1.0.
+func (p *processor) isJSONLD10() bool {
+	return p.opts.ProcessingMode == "json-ld-1.0"
+}
+
+// checkProcessingMode checks that a context which has already been processed
+// doesn't use features unsupported by the processing mode.
+func (p *processor) checkProcessingMode(ctx *Context) error {
+	if !p.isJSONLD10() || ctx == nil {
+		return nil
+	}
+	if ctx.jsonLD11 || ctx.Direction != "" {
+		return errorf("processing mode conflict", "context requires JSON-LD 1.1")
+	}
+	for k, term := range ctx.Terms {
+		if termRequiresJSONLD11(k, term) {
+			return errorf("processing mode conflict", "term %q requires JSON-LD 1.1", k)
+		}
+	}
+	return nil
+}
+
+func newProcessor(opts *Options) *processor {
+	if opts == nil {
+		opts = new(Options)
+	}
+	return &processor{
+		opts: opts,
+		contexts: make(map[string]*Context),
+		scopedContexts: make(map[scopedContextKey]*Context),
+	}
+}
+
 // Unmarshal parses the JSON-LD-encoded data and stores the result in the value
 // pointed to by v.
 //
@@ -39,12 +160,40 @@ type Type struct {
 //    resource must have the given type or else Unmarshal returns an error.
 //  * If the struct has a field whose tag is "@id", Unmarshal records the
 //    resource URI in that field.
+//  * If the struct has a field whose tag is "@type", Unmarshal records the
+//    resource type URIs in that field.
+//  * Tags can refer to keyword aliases defined in the context, such as "id"
+//    for "@id".
+//  * If the struct has a slice field whose tag is "@included", Unmarshal
+//    records the included resources in that field. References to included
+//    resources are replaced with the resources themselves.
 //  * If the resource has a property whose URI matches a tag formatted as
-//    "property-URI", the property value is recorded in that field.
+//    "property-URI", the property value is recorded in that field. Tags can
+//    also refer to terms of the context. Terms of the scoped context of a
+//    property apply to the fields of the property value.
+//  * If the tag is formatted as "property-URI,reverse", or if it refers to a
+//    reverse term of the context, the field contains a resource which has the
+//    resource as a value of the property.
+//  * If the field is a slice or an array, all values of the property are
+//    recorded in that field. If the property contains a list, the list items
+//    are recorded in order. Marshal encodes such fields as lists if the tag
+//    has the "list" option or if it refers to a term of the context with a
+//    @list container.
+//  * Language-tagged strings and strings with a base direction can be
+//    recorded in LangString fields. When recorded in string fields, the
+//    language and the direction are dropped.
+//  * JSON literals, ie. values whose type is @json, are recorded verbatim in
+//    json.RawMessage fields, in their canonical form (RFC 8785).
+//  * If the field is a map with string keys, values are recorded with their
+//    language, index, node identifier or type as key, depending on the
+//    container specified by the tag option ("language", "index", "id" or
+//    "type") or the context term. Values without a key use the "@none" key.
 //
 // To unmarshal JSON-LD into an interface value, Unmarshal uses the same rules
 // as the encoding/json package, except for resources which are stored as
-// *Resource.
+// *Resource, lists which are stored as List, language-tagged strings which
+// are stored as LangString, indexed values which are stored as Indexed and
+// JSON literals which are stored as json.RawMessage.
 func Unmarshal(b []byte, v interface{}) error {
 	return UnmarshalWithContext(b, v, nil)
 }
@@ -1,6 +1,7 @@
 package jsonld
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"reflect"
@@ -244,6 +245,513 @@ var example20Out = &foafPerson{
 	Depiction: &Resource{ID: "http://twitter.com/account/profile_image/markuslanthaler"},
 }
 
+const exampleBlankNodes = `{
+  "@context": {"knows": "http://xmlns.com/foaf/0.1/knows"},
+  "@id": "_:alice",
+  "knows": {"@id": "_:bob", "knows": {"@id": "_:alice"}}
+}`
+
+var exampleBlankNodesResource = &Resource{
+	ID: "_:alice",
+	Props: Props{
+		"http://xmlns.com/foaf/0.1/knows": {&Resource{
+			ID: "_:bob",
+			Props: Props{
+				"http://xmlns.com/foaf/0.1/knows": {&Resource{ID: "_:alice"}},
+			},
+		}},
+	},
+}
+
+const exampleSharedBlankNode = `{
+  "@id": "_:b0",
+  "http://xmlns.com/foaf/0.1/knows": [
+    {"http://xmlns.com/foaf/0.1/knows": {"@id": "_:b0"}},
+    {"@id": "_:b1"}
+  ]
+}`
+
+func newSharedBlankNode() *Resource {
+	alice := new(Resource)
+	bob := &Resource{Props: Props{"http://xmlns.com/foaf/0.1/knows": {alice}}}
+	carol := &Resource{ID: "_:carol"}
+	alice.Props = Props{"http://xmlns.com/foaf/0.1/knows": {bob, carol}}
+	return alice
+}
+
+type familyMember struct {
+	ID string `jsonld:"@id"`
+	Name string `jsonld:"http://schema.org/name"`
+	Child *familyMember `jsonld:"http://example.org/parent,reverse"`
+}
+
+const exampleReverse = `{
+  "@id": "http://example.org/alice",
+  "http://schema.org/name": "Alice",
+  "@reverse": {
+    "http://example.org/parent": {
+      "@id": "http://example.org/bob",
+      "http://schema.org/name": "Bob"
+    }
+  }
+}`
+
+const exampleReverseTerm = `{
+  "@context": {
+    "name": "http://schema.org/name",
+    "child": {"@reverse": "http://example.org/parent"}
+  },
+  "@id": "http://example.org/alice",
+  "name": "Alice",
+  "child": {
+    "@id": "http://example.org/bob",
+    "name": "Bob"
+  }
+}`
+
+var exampleReverseOut = &familyMember{
+	ID: "http://example.org/alice",
+	Name: "Alice",
+	Child: &familyMember{
+		ID: "http://example.org/bob",
+		Name: "Bob",
+	},
+}
+
+type familyMemberWithContext struct {
+	ID string `jsonld:"@id"`
+	Name string `jsonld:"name"`
+	Child *familyMemberWithContext `jsonld:"child"`
+}
+
+var familyContext = &Context{
+	Terms: map[string]*Resource{
+		"name": {ID: "http://schema.org/name"},
+		"child": {
+			ID: "http://example.org/parent",
+			Props: Props{"@reverse": {true}},
+		},
+	},
+}
+
+var exampleReverseOutWithContext = &familyMemberWithContext{
+	ID: "http://example.org/alice",
+	Name: "Alice",
+	Child: &familyMemberWithContext{
+		ID: "http://example.org/bob",
+		Name: "Bob",
+	},
+}
+
+type playlist struct {
+	ID string `jsonld:"@id"`
+	Tracks []string `jsonld:"http://schema.org/track,list"`
+	Keywords []string `jsonld:"http://schema.org/keywords"`
+}
+
+const examplePlaylist = `{
+  "@id": "http://example.org/playlist",
+  "http://schema.org/track": {"@list": ["c", "a", "b"]},
+  "http://schema.org/keywords": ["rock", "pop"]
+}`
+
+var examplePlaylistOut = &playlist{
+	ID: "http://example.org/playlist",
+	Tracks: []string{"c", "a", "b"},
+	Keywords: []string{"rock", "pop"},
+}
+
+var examplePlaylistResource = &Resource{
+	ID: "http://example.org/playlist",
+	Props: Props{
+		"http://schema.org/track": {List{"c", "a", "b"}},
+		"http://schema.org/keywords": {"rock", "pop"},
+	},
+}
+
+type playlistWithContext struct {
+	ID string `jsonld:"@id"`
+	Tracks []string `jsonld:"tracks"`
+	Keywords []string `jsonld:"keywords"`
+}
+
+const examplePlaylistWithContext = `{
+  "@context": {
+    "tracks": {"@id": "http://schema.org/track", "@container": "@list"},
+    "keywords": {"@id": "http://schema.org/keywords", "@container": "@set"}
+  },
+  "@id": "http://example.org/playlist",
+  "tracks": ["c", "a", "b"],
+  "keywords": ["rock"]
+}`
+
+var playlistContext = &Context{
+	Terms: map[string]*Resource{
+		"tracks": {
+			ID: "http://schema.org/track",
+			Props: Props{"@container": {"@list"}},
+		},
+		"keywords": {
+			ID: "http://schema.org/keywords",
+			Props: Props{"@container": {"@set"}},
+		},
+	},
+}
+
+var examplePlaylistWithContextOut = &playlistWithContext{
+	ID: "http://example.org/playlist",
+	Tracks: []string{"c", "a", "b"},
+	Keywords: []string{"rock"},
+}
+
+type book struct {
+	ID string `jsonld:"@id"`
+	Name LangString `jsonld:"http://schema.org/name"`
+	Author string `jsonld:"http://schema.org/author"`
+	AlternateNames []LangString `jsonld:"http://schema.org/alternateName"`
+}
+
+const exampleLangString = `{
+  "@context": {
+    "@language": "en",
+    "name": "http://schema.org/name",
+    "author": {"@id": "http://schema.org/author", "@language": null}
+  },
+  "@id": "http://example.org/book",
+  "name": "The Title",
+  "author": "Someone",
+  "http://schema.org/alternateName": [
+    {"@value": "Le Titre", "@language": "fr"},
+    {"@value": "Der Titel", "@language": "de"}
+  ]
+}`
+
+var bookContext = &Context{
+	Lang: "en",
+	Terms: map[string]*Resource{
+		"name": {ID: "http://schema.org/name"},
+		"author": {
+			ID: "http://schema.org/author",
+			Props: Props{"@language": {nil}},
+		},
+	},
+}
+
+var exampleLangStringOut = &book{
+	ID: "http://example.org/book",
+	Name: LangString{Value: "The Title", Language: "en"},
+	Author: "Someone",
+	AlternateNames: []LangString{
+		{Value: "Le Titre", Language: "fr"},
+		{Value: "Der Titel", Language: "de"},
+	},
+}
+
+var exampleLangStringResource = &Resource{
+	ID: "http://example.org/book",
+	Props: Props{
+		"http://schema.org/name": {LangString{Value: "The Title", Language: "en"}},
+		"http://schema.org/author": {"Someone"},
+		"http://schema.org/alternateName": {
+			LangString{Value: "Le Titre", Language: "fr"},
+			LangString{Value: "Der Titel", Language: "de"},
+		},
+	},
+}
+
+var exampleDirectionResource = &Resource{
+	Props: Props{
+		"http://example.org/title": {LangString{Value: "كتاب", Language: "ar", Direction: "rtl"}},
+		"http://example.org/english": {LangString{Value: "Book", Language: "en", Direction: "ltr"}},
+		"http://example.org/code": {"B-1"},
+		"http://example.org/other": {LangString{Value: "ספר", Language: "he", Direction: "rtl"}},
+	},
+}
+
+const exampleBase = `{
+  "@context": {
+    "@base": "http://example.org/people/",
+    "@vocab": "http://schema.org/"
+  },
+  "@id": "alice",
+  "knows": {"@id": "../bots/bob"}
+}`
+
+var exampleBaseResource = &Resource{
+	ID: "http://example.org/people/alice",
+	Props: Props{
+		"http://schema.org/knows": {&Resource{ID: "http://example.org/bots/bob"}},
+	},
+}
+
+var baseContext = &Context{
+	Base: "http://example.org/people/",
+	Vocab: "http://schema.org/",
+}
+
+var directionContext = &Context{
+	Lang: "ar",
+	Direction: "rtl",
+	Vocab: "http://example.org/",
+	Terms: map[string]*Resource{
+		"english": {
+			ID: "http://example.org/english",
+			Props: Props{"@language": {"en"}, "@direction": {"ltr"}},
+		},
+		"code": {
+			ID: "http://example.org/code",
+			Props: Props{"@language": {nil}, "@direction": {nil}},
+		},
+	},
+}
+
+type labeledResource struct {
+	ID string `jsonld:"@id"`
+	Name string `jsonld:"http://example.org/name"`
+	MainLabel string `jsonld:"main_label"`
+	OtherLabel string `jsonld:"other_label"`
+}
+
+var nestContext = &Context{
+	Vocab: "http://example.org/",
+	Terms: map[string]*Resource{
+		"labels": {ID: "@nest"},
+		"main_label": {
+			ID: "http://example.org/mainLabel",
+			Props: Props{"@nest": {"labels"}},
+		},
+		"other_label": {
+			ID: "http://example.org/otherLabel",
+			Props: Props{"@nest": {"labels"}},
+		},
+	},
+}
+
+var exampleNestOut = &labeledResource{
+	ID: "http://example.org/resource",
+	Name: "Resource",
+	MainLabel: "Main label",
+	OtherLabel: "Other label",
+}
+
+type article struct {
+	ID string `jsonld:"@id"`
+	Title string `jsonld:"http://example.org/title"`
+	Author *foafAgent `jsonld:"http://example.org/author"`
+	Included []*Resource `jsonld:"@included"`
+}
+
+type foafAgent struct {
+	ID string `jsonld:"@id"`
+	Name string `jsonld:"http://example.org/name"`
+}
+
+func newExampleIncludedResource() *Resource {
+	author := &Resource{
+		ID: "http://example.org/people/9",
+		Props: Props{"http://example.org/name": {"Dan"}},
+	}
+	publisher := &Resource{
+		ID: "http://example.org/orgs/1",
+		Props: Props{"http://example.org/name": {"W3C"}},
+	}
+	return &Resource{
+		ID: "http://example.org/articles/1",
+		Props: Props{
+			"http://example.org/title": {"JSON-LD"},
+			"http://example.org/author": {author},
+			"http://example.org/publisher": {publisher},
+		},
+		Included: []*Resource{author, publisher},
+	}
+}
+
+var exampleIncludedOut = &article{
+	ID: "http://example.org/articles/1",
+	Title: "JSON-LD",
+	Author: &foafAgent{ID: "http://example.org/people/9", Name: "Dan"},
+	Included: newExampleIncludedResource().Included,
+}
+
+var vocabContext = &Context{Vocab: "http://example.org/"}
+
+type service struct {
+	ID string `jsonld:"@id"`
+	Config json.RawMessage `jsonld:"config"`
+}
+
+var serviceContext = &Context{
+	Vocab: "http://example.org/",
+	Terms: map[string]*Resource{
+		"config": {
+			ID: "http://example.org/config",
+			Props: Props{propType: {"@json"}},
+		},
+	},
+}
+
+var exampleJSONLiteralOut = &service{
+	ID: "http://example.org/s",
+	Config: json.RawMessage(`{"a":null,"b":[1,1e+21,0.5],"c":"x"}`),
+}
+
+type review struct {
+	Author *reviewPerson `jsonld:"author"`
+	Reviewer *reviewPerson `jsonld:"reviewer"`
+}
+
+type reviewPerson struct {
+	Name string `jsonld:"name"`
+	Knows *reviewPerson `jsonld:"knows"`
+}
+
+const exampleScopedContextsStruct = `{
+  "@context": {
+    "@vocab": "http://example.org/",
+    "author": {
+      "@id": "http://example.org/author",
+      "@context": {"name": "http://xmlns.com/foaf/0.1/name"}
+    },
+    "reviewer": {
+      "@id": "http://example.org/reviewer",
+      "@context": {"@propagate": false, "name": "http://schema.org/name"}
+    }
+  },
+  "author": {"name": "Dan", "knows": {"name": "Alice"}},
+  "reviewer": {"name": "Eve", "knows": {"name": "Bob"}}
+}`
+
+var reviewContext = &Context{
+	Vocab: "http://example.org/",
+	Terms: map[string]*Resource{
+		"author": {
+			ID: "http://example.org/author",
+			Props: Props{"@context": {map[string]interface{}{
+				"name": "http://xmlns.com/foaf/0.1/name",
+			}}},
+		},
+		"reviewer": {
+			ID: "http://example.org/reviewer",
+			Props: Props{"@context": {map[string]interface{}{
+				"@propagate": false,
+				"name": "http://schema.org/name",
+			}}},
+		},
+	},
+}
+
+var exampleScopedContextsOut = &review{
+	Author: &reviewPerson{Name: "Dan", Knows: &reviewPerson{Name: "Alice"}},
+	Reviewer: &reviewPerson{Name: "Eve", Knows: &reviewPerson{Name: "Bob"}},
+}
+
+type activity struct {
+	ID string `jsonld:"id"`
+	Types []string `jsonld:"type"`
+	Content string `jsonld:"content"`
+}
+
+const exampleKeywordAliases = `{
+  "@context": {
+    "@vocab": "https://www.w3.org/ns/activitystreams#",
+    "id": "@id",
+    "type": {"@id": "@type", "@container": "@set"}
+  },
+  "id": "http://example.org/note",
+  "type": ["Note"],
+  "content": "Hello"
+}`
+
+var activityContext = &Context{
+	Vocab: "https://www.w3.org/ns/activitystreams#",
+	Terms: map[string]*Resource{
+		"id": {ID: "@id"},
+		"type": {ID: "@type", Props: Props{"@container": {"@set"}}},
+	},
+}
+
+var exampleKeywordAliasesOut = &activity{
+	ID: "http://example.org/note",
+	Types: []string{"https://www.w3.org/ns/activitystreams#Note"},
+	Content: "Hello",
+}
+
+type library struct {
+	ID string `jsonld:"@id"`
+	Titles map[string]string `jsonld:"titles"`
+	Notes map[string]string `jsonld:"notes"`
+	Authors map[string]*Resource `jsonld:"authors"`
+	Items map[string][]*Resource `jsonld:"items"`
+}
+
+const exampleContainerMapsStruct = `{
+  "@context": {
+    "@vocab": "http://example.org/",
+    "titles": {"@id": "http://example.org/title", "@container": "@language"},
+    "notes": {"@id": "http://example.org/note", "@container": "@index"},
+    "authors": {"@id": "http://example.org/author", "@container": "@id"},
+    "items": {"@id": "http://example.org/item", "@container": "@type", "@type": "@id"}
+  },
+  "@id": "http://example.org/library",
+  "titles": {"en": "Library", "fr": "Bibliothèque"},
+  "notes": {"a": "First", "b": "Second"},
+  "authors": {"http://example.org/alice": {"name": "Alice"}},
+  "items": {
+    "Book": [{"name": "A"}, {"name": "B"}],
+    "Film": {"name": "C"}
+  }
+}`
+
+var libraryContext = &Context{
+	Vocab: "http://example.org/",
+	Terms: map[string]*Resource{
+		"titles": {
+			ID: "http://example.org/title",
+			Props: Props{"@container": {"@language"}},
+		},
+		"notes": {
+			ID: "http://example.org/note",
+			Props: Props{"@container": {"@index"}},
+		},
+		"authors": {
+			ID: "http://example.org/author",
+			Props: Props{"@container": {"@id"}},
+		},
+		"items": {
+			ID: "http://example.org/item",
+			Props: Props{"@container": {"@type"}, propType: {"@id"}},
+		},
+	},
+}
+
+func newLibraryItem(t, name string) *Resource {
+	return &Resource{Props: Props{
+		propType: {t},
+		"http://example.org/name": {name},
+	}}
+}
+
+var exampleContainerMapsOut = &library{
+	ID: "http://example.org/library",
+	Titles: map[string]string{"en": "Library", "fr": "Bibliothèque"},
+	Notes: map[string]string{"a": "First", "b": "Second"},
+	Authors: map[string]*Resource{
+		"http://example.org/alice": {
+			ID: "http://example.org/alice",
+			Props: Props{"http://example.org/name": {"Alice"}},
+		},
+	},
+	Items: map[string][]*Resource{
+		"http://example.org/Book": {
+			newLibraryItem("http://example.org/Book", "A"),
+			newLibraryItem("http://example.org/Book", "B"),
+		},
+		"http://example.org/Film": {
+			newLibraryItem("http://example.org/Film", "C"),
+		},
+	},
+}
+
 var unmarshalTests = []struct{
 	jsonld string
 	in interface{}
@@ -318,6 +826,103 @@ var unmarshalTests = []struct{
 		ctx: personContext,
 		out: example2OutWithContext,
 	},
+	{
+		jsonld: exampleBlankNodes,
+		in: &Resource{},
+		out: exampleBlankNodesResource,
+	},
+	{
+		jsonld: exampleReverse,
+		in: &familyMember{},
+		out: exampleReverseOut,
+	},
+	{
+		jsonld: exampleReverseTerm,
+		in: &familyMember{},
+		out: exampleReverseOut,
+	},
+	{
+		jsonld: exampleReverse,
+		in: &familyMemberWithContext{},
+		ctx: familyContext,
+		out: exampleReverseOutWithContext,
+	},
+	{
+		jsonld: examplePlaylist,
+		in: &Resource{},
+		out: examplePlaylistResource,
+	},
+	{
+		jsonld: examplePlaylist,
+		in: &playlist{},
+		out: examplePlaylistOut,
+	},
+	{
+		jsonld: examplePlaylistWithContext,
+		in: &playlistWithContext{},
+		ctx: playlistContext,
+		out: examplePlaylistWithContextOut,
+	},
+	{
+		jsonld: exampleLangString,
+		in: &Resource{},
+		out: exampleLangStringResource,
+	},
+	{
+		jsonld: exampleLangString,
+		in: &book{},
+		out: exampleLangStringOut,
+	},
+	{
+		jsonld: exampleDirection,
+		in: &Resource{},
+		out: exampleDirectionResource,
+	},
+	{
+		jsonld: exampleBase,
+		in: &Resource{},
+		out: exampleBaseResource,
+	},
+	{
+		jsonld: exampleIncluded,
+		in: &Resource{},
+		out: newExampleIncludedResource(),
+	},
+	{
+		jsonld: exampleIncluded,
+		in: &article{},
+		out: exampleIncludedOut,
+	},
+	{
+		jsonld: exampleJSONLiteral,
+		in: &service{},
+		ctx: serviceContext,
+		out: exampleJSONLiteralOut,
+	},
+	{
+		jsonld: exampleScopedContextsStruct,
+		in: &review{},
+		ctx: reviewContext,
+		out: exampleScopedContextsOut,
+	},
+	{
+		jsonld: exampleKeywordAliases,
+		in: &activity{},
+		ctx: activityContext,
+		out: exampleKeywordAliasesOut,
+	},
+	{
+		jsonld: exampleNest,
+		in: &labeledResource{},
+		ctx: nestContext,
+		out: exampleNestOut,
+	},
+	{
+		jsonld: exampleContainerMapsStruct,
+		in: &library{},
+		ctx: libraryContext,
+		out: exampleContainerMapsOut,
+	},
 }
 
 func TestUnmarshal(t *testing.T) {
@@ -383,6 +988,319 @@ var marshalTests = []struct{
 			},
 		},
 	},
+	{
+		jsonld: exampleReverse,
+		in: exampleReverseOut,
+	},
+	{
+		jsonld: exampleReverseTerm,
+		in: exampleReverseOutWithContext,
+		ctx: familyContext,
+	},
+	{
+		jsonld: examplePlaylist,
+		in: examplePlaylistResource,
+	},
+	{
+		jsonld: examplePlaylist,
+		in: examplePlaylistOut,
+	},
+	{
+		jsonld: examplePlaylistWithContext,
+		in: examplePlaylistWithContextOut,
+		ctx: playlistContext,
+	},
+	{
+		jsonld: exampleLangString,
+		in: exampleLangStringResource,
+		ctx: bookContext,
+	},
+	{
+		jsonld: exampleLangString,
+		in: exampleLangStringOut,
+		ctx: bookContext,
+	},
+	{
+		jsonld: exampleDirection,
+		in: exampleDirectionResource,
+		ctx: directionContext,
+	},
+	{
+		jsonld: exampleBase,
+		in: exampleBaseResource,
+		ctx: baseContext,
+	},
+	{
+		jsonld: exampleIncluded,
+		in: newExampleIncludedResource(),
+		ctx: vocabContext,
+	},
+	{
+		jsonld: exampleJSONLiteral,
+		in: exampleJSONLiteralOut,
+		ctx: serviceContext,
+	},
+	{
+		jsonld: exampleScopedContextsStruct,
+		in: exampleScopedContextsOut,
+		ctx: reviewContext,
+	},
+	{
+		jsonld: exampleKeywordAliases,
+		in: exampleKeywordAliasesOut,
+		ctx: activityContext,
+	},
+	{
+		jsonld: exampleNest,
+		in: exampleNestOut,
+		ctx: nestContext,
+	},
+	{
+		jsonld: exampleContainerMapsStruct,
+		in: exampleContainerMapsOut,
+		ctx: libraryContext,
+	},
+}
+
+func TestMarshal_blankNodes(t *testing.T) {
+	var want interface{}
+	if err := json.Unmarshal([]byte(exampleSharedBlankNode), &want); err != nil {
+		t.Fatalf("json.Unmarshal(want) = %v", err)
+	}
+
+	b, err := Marshal(newSharedBlankNode())
+	if err != nil {
+		t.Fatalf("Marshal() = %v", err)
+	}
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		t.Fatalf("json.Unmarshal(got = %v) = %v", string(b), err)
+	}
+	if !reflect.DeepEqual(v, want) {
+		t.Errorf("Marshal() = %v, want %v", string(b), exampleSharedBlankNode)
+	}
+}
+
+func TestEncoder_processingMode(t *testing.T) {
+	tests := []struct{
+		name string
+		v interface{}
+		ctx *Context
+	}{
+		{name: "direction", v: exampleDirectionResource},
+		{name: "context direction", v: exampleLangStringResource, ctx: &Context{Direction: "ltr"}},
+		{name: "included", v: newExampleIncludedResource()},
+		{name: "JSON literal", v: exampleJSONLiteralOut},
+		{name: "id map", v: exampleContainerMapsOut, ctx: libraryContext},
+		{name: "protected term", v: exampleLangStringResource, ctx: &Context{
+			Terms: map[string]*Resource{
+				"title": {ID: "http://example.org/title", Props: Props{"@protected": {true}}},
+			},
+		}},
+		{name: "list of lists", v: &Resource{
+			ID: "http://example.org/a",
+			Props: Props{"http://example.org/p": {List{List{"x"}}}},
+		}},
+	}
+	for _, test := range tests {
+		var b bytes.Buffer
+		enc := NewEncoder(&b)
+		enc.Context = test.ctx
+		enc.ProcessingMode = "json-ld-1.0"
+		if err := enc.Encode(test.v); err == nil {
+			t.Errorf("%v: Encode() = %v, want an error", test.name, b.String())
+		}
+	}
+
+	var b bytes.Buffer
+	enc := NewEncoder(&b)
+	enc.ProcessingMode = "json-ld-1.0"
+	if err := enc.Encode(exampleLangStringResource); err != nil {
+		t.Errorf("Encode() = %v", err)
+	}
+}
+
+func TestRemoteScopedContext(t *testing.T) {
+	ctx := &Context{
+		Vocab: "http://example.org/",
+		Terms: map[string]*Resource{
+			"author": {
+				ID: "http://example.org/author",
+				Props: Props{"@context": {"http://example.org/foaf.jsonld"}},
+			},
+		},
+	}
+	fetch := func(url string) (*Context, error) {
+		if url != "http://example.org/foaf.jsonld" {
+			return nil, fmt.Errorf("invalid context URL: %v", url)
+		}
+		return &Context{
+			URL: url,
+			Terms: map[string]*Resource{
+				"name": {ID: "http://xmlns.com/foaf/0.1/name"},
+			},
+		}, nil
+	}
+	in := &review{Author: &reviewPerson{Name: "Dan"}}
+
+	var b bytes.Buffer
+	enc := NewEncoder(&b)
+	enc.Context = ctx
+	enc.FetchContext = fetch
+	if err := enc.Encode(in); err != nil {
+		t.Fatalf("Encode() = %v", err)
+	}
+
+	var r Resource
+	dec := NewDecoder(bytes.NewReader(b.Bytes()))
+	dec.FetchContext = fetch
+	if err := dec.Decode(&r); err != nil {
+		t.Fatalf("Decode() = %v", err)
+	}
+	author, _ := r.Props.Get("http://example.org/author").(*Resource)
+	if author == nil || author.Props.Get("http://xmlns.com/foaf/0.1/name") != "Dan" {
+		t.Errorf("Encode() = %v, want the name to use the remote scoped context", b.String())
+	}
+
+	dec = NewDecoder(bytes.NewReader(b.Bytes()))
+	dec.Context = ctx
+	dec.FetchContext = fetch
+	var out review
+	if err := dec.Decode(&out); err != nil {
+		t.Fatalf("Decode() = %v", err)
+	} else if !reflect.DeepEqual(&out, in) {
+		t.Errorf("Decode() = %#v, want %#v", &out, in)
+	}
+}
+
+func TestResource_IsBlank(t *testing.T) {
+	tests := map[string]bool{
+		"": true,
+		"_:b0": true,
+		"http://example.org/": false,
+	}
+	for id, want := range tests {
+		r := &Resource{ID: id}
+		if got := r.IsBlank(); got != want {
+			t.Errorf("Resource{ID: %q}.IsBlank() = %v, want %v", id, got, want)
+		}
+	}
+}
+
+const exampleDataset = `{
+  "@context": {
+    "@vocab": "http://schema.org/",
+    "knows": {"@type": "@id"}
+  },
+  "@graph": [
+    {
+      "@id": "http://example.org/alice",
+      "name": "Alice"
+    },
+    {
+      "@id": "http://example.org/graph",
+      "@graph": {
+        "@id": "http://example.org/bob",
+        "name": "Bob",
+        "knows": "http://example.org/alice"
+      },
+      "name": "Bob's graph"
+    }
+  ]
+}`
+
+var exampleDatasetOut = &Dataset{
+	Default: Graph{
+		{
+			ID: "http://example.org/alice",
+			Props: Props{"http://schema.org/name": {"Alice"}},
+		},
+		{
+			ID: "http://example.org/graph",
+			Props: Props{"http://schema.org/name": {"Bob's graph"}},
+		},
+	},
+	Named: map[string]Graph{
+		"http://example.org/graph": {
+			{
+				ID: "http://example.org/bob",
+				Props: Props{
+					"http://schema.org/name": {"Bob"},
+					"http://schema.org/knows": {&Resource{ID: "http://example.org/alice"}},
+				},
+			},
+		},
+	},
+}
+
+func TestDecode_dataset(t *testing.T) {
+	var ds Dataset
+	if err := Unmarshal([]byte(exampleDataset), &ds); err != nil {
+		t.Fatalf("Unmarshal() = %v", err)
+	}
+	if !reflect.DeepEqual(&ds, exampleDatasetOut) {
+		t.Errorf("Unmarshal() = %#v, want %#v", &ds, exampleDatasetOut)
+	}
+
+	var people []person
+	if err := Unmarshal([]byte(exampleDataset), &people); err != nil {
+		t.Fatalf("Unmarshal(slice) = %v", err)
+	}
+	want := []person{
+		{ID: "http://example.org/alice", Name: "Alice"},
+		{ID: "http://example.org/graph", Name: "Bob's graph"},
+	}
+	if !reflect.DeepEqual(people, want) {
+		t.Errorf("Unmarshal(slice) = %#v, want %#v", people, want)
+	}
+}
+
+func TestDecode_included(t *testing.T) {
+	var g Graph
+	if err := Unmarshal([]byte(exampleIncluded), &g); err != nil {
+		t.Fatalf("Unmarshal() = %v", err)
+	}
+
+	r := newExampleIncludedResource()
+	want := Graph{r, r.Included[0], r.Included[1]}
+	r.Included = nil
+	if !reflect.DeepEqual(g, want) {
+		t.Errorf("Unmarshal() = %#v, want %#v", g, want)
+	}
+}
+
+func TestEncode_dataset(t *testing.T) {
+	var want interface{}
+	if err := json.Unmarshal([]byte(exampleDataset), &want); err != nil {
+		t.Fatalf("json.Unmarshal(want) = %v", err)
+	}
+
+	ctx := &Context{
+		Vocab: "http://schema.org/",
+		Terms: map[string]*Resource{
+			"knows": {
+				ID: "http://schema.org/knows",
+				Props: Props{propType: {"@id"}},
+			},
+		},
+	}
+	b, err := MarshalWithContext(exampleDatasetOut, ctx)
+	if err != nil {
+		t.Fatalf("MarshalWithContext() = %v", err)
+	}
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		t.Fatalf("json.Unmarshal(got = %v) = %v", string(b), err)
+	}
+	// The context is formatted differently, only compare the graphs
+	delete(v.(map[string]interface{}), "@context")
+	delete(want.(map[string]interface{}), "@context")
+	// @graph values are always compacted to arrays
+	graph := want.(map[string]interface{})["@graph"].([]interface{})[1].(map[string]interface{})
+	graph["@graph"] = []interface{}{graph["@graph"]}
+	if !reflect.DeepEqual(v, want) {
+		t.Errorf("MarshalWithContext() = %v, want %v", string(b), exampleDataset)
+	}
 }
 
 func TestMarshalWithContext(t *testing.T) {
@@ -407,3 +1325,41 @@ func TestMarshalWithContext(t *testing.T) {
 		}
 	}
 }
+
+func TestEncoder_prefixLikeScheme(t *testing.T) {
+	ctx := &Context{
+		Vocab: "http://example.org/",
+		Terms: map[string]*Resource{
+			"urn": {ID: "http://example.org/urn/"},
+		},
+	}
+
+	// The vocabulary-relative form of the property would be read back as a
+	// compact IRI
+	in := &Resource{
+		ID: "http://example.org/a",
+		Props: Props{"http://example.org/urn:isbn": {"x"}},
+	}
+	var b bytes.Buffer
+	enc := NewEncoder(&b)
+	enc.Context = ctx
+	if err := enc.Encode(in); err != nil {
+		t.Fatalf("Encode() = %v", err)
+	}
+	var out Resource
+	if err := NewDecoder(bytes.NewReader(b.Bytes())).Decode(&out); err != nil {
+		t.Fatalf("Decode() = %v", err)
+	} else if !reflect.DeepEqual(&out, in) {
+		t.Errorf("Decode(%v) = %#v, want %#v", b.String(), &out, in)
+	}
+
+	// An IRI whose scheme is a prefix can't be represented
+	b.Reset()
+	err := enc.Encode(&Resource{
+		ID: "urn:isbn:0451450523",
+		Props: Props{"http://example.org/title": {"x"}},
+	})
+	if err, ok := err.(*Error); !ok || err.Code != "IRI confused with prefix" {
+		t.Errorf("Encode() = %v, want an IRI confused with prefix error", err)
+	}
+}
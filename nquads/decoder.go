@@ -0,0 +1,314 @@
+package nquads
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/emersion/go-jsonld"
+)
+
+// Decoder reads N-Quads statements.
+type Decoder struct {
+	r *bufio.Reader
+	line int
+}
+
+// NewDecoder creates a new N-Quads decoder.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: bufio.NewReader(r)}
+}
+
+// Decode reads the next statement. It returns io.EOF when there are no more
+// statements.
+func (d *Decoder) Decode() (*jsonld.Quad, error) {
+	for {
+		s, err := d.r.ReadString('\n')
+		if err == io.EOF && s == "" {
+			return nil, io.EOF
+		} else if err != nil && err != io.EOF {
+			return nil, err
+		}
+		d.line++
+
+		s = strings.TrimSuffix(strings.TrimSuffix(s, "\n"), "\r")
+		p := &lineParser{line: d.line, s: []rune(s)}
+		q, err := p.parseStatement()
+		if err != nil {
+			return nil, err
+		} else if q != nil {
+			return q, nil
+		}
+	}
+}
+
+// lineParser parses a single line.
+type lineParser struct {
+	line int
+	s []rune
+	pos int
+}
+
+func (p *lineParser) errorf(format string, v ...interface{}) error {
+	return &SyntaxError{
+		Line: p.line,
+		Column: p.pos + 1,
+		Msg: fmt.Sprintf(format, v...),
+	}
+}
+
+func (p *lineParser) eof() bool {
+	return p.pos >= len(p.s)
+}
+
+func (p *lineParser) peek() rune {
+	if p.eof() {
+		return 0
+	}
+	return p.s[p.pos]
+}
+
+func (p *lineParser) skipSpace() {
+	for !p.eof() && (p.s[p.pos] == ' ' || p.s[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// parseStatement parses a statement. It returns nil if the line is empty or
+// only contains a comment.
+func (p *lineParser) parseStatement() (*jsonld.Quad, error) {
+	p.skipSpace()
+	if p.eof() || p.peek() == '#' {
+		return nil, nil
+	}
+
+	var q jsonld.Quad
+	var err error
+	if q.Subject, err = p.parseResource(); err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.peek() != '<' {
+		return nil, p.errorf("expected predicate IRI")
+	}
+	if q.Predicate, err = p.parseIRI(); err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if q.Object, err = p.parseObject(); err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.peek() != '.' {
+		if q.Graph, err = p.parseResource(); err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+	}
+	if p.peek() != '.' {
+		return nil, p.errorf("expected '.'")
+	}
+	p.pos++
+	p.skipSpace()
+	if !p.eof() && p.peek() != '#' {
+		return nil, p.errorf("unexpected character %q after statement", p.peek())
+	}
+
+	return &q, nil
+}
+
+func (p *lineParser) parseResource() (jsonld.RDFTerm, error) {
+	switch p.peek() {
+	case '<':
+		return p.parseIRI()
+	case '_':
+		return p.parseBlankNode()
+	default:
+		return nil, p.errorf("expected IRI or blank node")
+	}
+}
+
+func (p *lineParser) parseObject() (jsonld.RDFTerm, error) {
+	if p.peek() == '"' {
+		return p.parseLiteral()
+	}
+	return p.parseResource()
+}
+
+func (p *lineParser) parseIRI() (jsonld.IRI, error) {
+	start := p.pos
+	p.pos++ // '<'
+
+	var sb strings.Builder
+	for {
+		if p.eof() {
+			return "", p.errorf("unterminated IRI")
+		}
+		c := p.s[p.pos]
+		switch {
+		case c == '>':
+			p.pos++
+			iri := sb.String()
+			if !jsonld.IsAbsoluteIRI(iri) {
+				p.pos = start
+				return "", p.errorf("relative IRI %q", iri)
+			}
+			return jsonld.IRI(iri), nil
+		case c == '\\':
+			r, err := p.parseUCHAR()
+			if err != nil {
+				return "", err
+			}
+			sb.WriteRune(r)
+		case c <= 0x20 || strings.ContainsRune("<\"{}|^`", c):
+			return "", p.errorf("invalid character %q in IRI", c)
+		default:
+			sb.WriteRune(c)
+			p.pos++
+		}
+	}
+}
+
+func (p *lineParser) parseBlankNode() (jsonld.BlankNode, error) {
+	if p.pos+1 >= len(p.s) || p.s[p.pos+1] != ':' {
+		return "", p.errorf("expected blank node")
+	}
+	p.pos += 2
+
+	start := p.pos
+	for !p.eof() && isBlankNodeChar(p.s[p.pos], p.pos == start) {
+		p.pos++
+	}
+	// A label cannot end with a dot
+	for p.pos > start && p.s[p.pos-1] == '.' {
+		p.pos--
+	}
+	if p.pos == start {
+		return "", p.errorf("empty blank node label")
+	}
+	return jsonld.BlankNode(p.s[start:p.pos]), nil
+}
+
+func isBlankNodeChar(c rune, first bool) bool {
+	switch {
+	case c == '_' || unicode.IsLetter(c) || unicode.IsDigit(c):
+		return true
+	case first:
+		return false
+	default:
+		return c == '-' || c == '.' || c == 0xB7 || unicode.Is(unicode.Mn, c) || unicode.Is(unicode.Pc, c)
+	}
+}
+
+func (p *lineParser) parseLiteral() (jsonld.Literal, error) {
+	p.pos++ // '"'
+
+	var sb strings.Builder
+	for {
+		if p.eof() {
+			return jsonld.Literal{}, p.errorf("unterminated literal")
+		}
+		c := p.s[p.pos]
+		if c == '"' {
+			p.pos++
+			break
+		} else if c != '\\' {
+			sb.WriteRune(c)
+			p.pos++
+			continue
+		}
+
+		if p.pos+1 >= len(p.s) {
+			return jsonld.Literal{}, p.errorf("unterminated escape sequence")
+		}
+		var r rune
+		switch p.s[p.pos+1] {
+		case 't':
+			r = '\t'
+		case 'b':
+			r = '\b'
+		case 'n':
+			r = '\n'
+		case 'r':
+			r = '\r'
+		case 'f':
+			r = '\f'
+		case '"', '\'', '\\':
+			r = p.s[p.pos+1]
+		case 'u', 'U':
+			var err error
+			if r, err = p.parseUCHAR(); err != nil {
+				return jsonld.Literal{}, err
+			}
+			sb.WriteRune(r)
+			continue
+		default:
+			return jsonld.Literal{}, p.errorf("invalid escape sequence")
+		}
+		sb.WriteRune(r)
+		p.pos += 2
+	}
+
+	lit := jsonld.Literal{Value: sb.String(), Datatype: xsdString}
+	switch p.peek() {
+	case '@':
+		p.pos++
+		start := p.pos
+		for !p.eof() && isLangChar(p.s[p.pos], p.pos == start) {
+			p.pos++
+		}
+		lang := string(p.s[start:p.pos])
+		if lang == "" || strings.HasSuffix(lang, "-") || strings.Contains(lang, "--") {
+			p.pos = start
+			return jsonld.Literal{}, p.errorf("invalid language tag")
+		}
+		lit.Language = lang
+		lit.Datatype = rdfLangString
+	case '^':
+		if p.pos+2 >= len(p.s) || p.s[p.pos+1] != '^' || p.s[p.pos+2] != '<' {
+			return jsonld.Literal{}, p.errorf("expected datatype IRI")
+		}
+		p.pos += 2
+		var err error
+		if lit.Datatype, err = p.parseIRI(); err != nil {
+			return jsonld.Literal{}, err
+		}
+	}
+	return lit, nil
+}
+
+func isLangChar(c rune, first bool) bool {
+	isAlpha := (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+	if first {
+		return isAlpha
+	}
+	return isAlpha || (c >= '0' && c <= '9') || c == '-'
+}
+
+// parseUCHAR parses a \uXXXX or \UXXXXXXXX escape sequence.
+func (p *lineParser) parseUCHAR() (rune, error) {
+	if p.pos+1 >= len(p.s) {
+		return 0, p.errorf("unterminated escape sequence")
+	}
+	var n int
+	switch p.s[p.pos+1] {
+	case 'u':
+		n = 4
+	case 'U':
+		n = 8
+	default:
+		return 0, p.errorf("invalid escape sequence")
+	}
+	if p.pos+2+n > len(p.s) {
+		return 0, p.errorf("unterminated escape sequence")
+	}
+	r, err := strconv.ParseUint(string(p.s[p.pos+2:p.pos+2+n]), 16, 32)
+	if err != nil {
+		return 0, p.errorf("invalid escape sequence")
+	}
+	p.pos += 2 + n
+	return rune(r), nil
+}
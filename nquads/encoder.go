@@ -0,0 +1,37 @@
+package nquads
+
+import (
+	"errors"
+	"io"
+
+	"github.com/emersion/go-jsonld"
+)
+
+// Encoder writes N-Quads statements.
+type Encoder struct {
+	w io.Writer
+}
+
+// NewEncoder creates a new N-Quads encoder.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// Encode writes a statement, followed by a newline.
+func (e *Encoder) Encode(q *jsonld.Quad) error {
+	if q.Subject == nil || q.Predicate == nil || q.Object == nil {
+		return errors.New("nquads: incomplete statement")
+	}
+	if _, ok := q.Subject.(jsonld.Literal); ok {
+		return errors.New("nquads: literal used as subject")
+	}
+	if _, ok := q.Predicate.(jsonld.IRI); !ok {
+		return errors.New("nquads: predicate must be an IRI")
+	}
+	if _, ok := q.Graph.(jsonld.Literal); ok {
+		return errors.New("nquads: literal used as graph name")
+	}
+
+	_, err := io.WriteString(e.w, q.String()+"\n")
+	return err
+}
@@ -0,0 +1,56 @@
+// Package nquads implements the N-Quads and N-Triples RDF formats, as defined
+// in https://www.w3.org/TR/n-quads/ and https://www.w3.org/TR/n-triples/.
+//
+// N-Triples is a subset of N-Quads: the decoder accepts both, and the encoder
+// produces N-Triples when all statements belong to the default graph.
+package nquads
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/emersion/go-jsonld"
+)
+
+const (
+	xsdString = "http://www.w3.org/2001/XMLSchema#string"
+	rdfLangString = "http://www.w3.org/1999/02/22-rdf-syntax-ns#langString"
+)
+
+// SyntaxError is returned when the input is not valid N-Quads.
+type SyntaxError struct {
+	Line, Column int
+	Msg string
+}
+
+func (err *SyntaxError) Error() string {
+	return fmt.Sprintf("nquads: line %v, column %v: %v", err.Line, err.Column, err.Msg)
+}
+
+// Unmarshal parses N-Quads data.
+func Unmarshal(b []byte) ([]jsonld.Quad, error) {
+	dec := NewDecoder(bytes.NewReader(b))
+	var quads []jsonld.Quad
+	for {
+		q, err := dec.Decode()
+		if err == io.EOF {
+			return quads, nil
+		} else if err != nil {
+			return nil, err
+		}
+		quads = append(quads, *q)
+	}
+}
+
+// Marshal returns the N-Quads encoding of quads.
+func Marshal(quads []jsonld.Quad) ([]byte, error) {
+	var b bytes.Buffer
+	enc := NewEncoder(&b)
+	for i := range quads {
+		if err := enc.Encode(&quads[i]); err != nil {
+			return nil, err
+		}
+	}
+	return b.Bytes(), nil
+}
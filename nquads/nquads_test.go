@@ -0,0 +1,84 @@
+package nquads
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/emersion/go-jsonld"
+)
+
+const testNQuads = `# A comment
+<http://example.org/s> <http://example.org/p> <http://example.org/o> .
+_:b0 <http://example.org/p> "a \"b\"\né"@en-US <http://example.org/g> .
+
+_:b0.1 <http://example.org/p> "42"^^<http://www.w3.org/2001/XMLSchema#integer> _:g. # trailing comment
+`
+
+var testQuads = []jsonld.Quad{
+	{
+		Subject: jsonld.IRI("http://example.org/s"),
+		Predicate: jsonld.IRI("http://example.org/p"),
+		Object: jsonld.IRI("http://example.org/o"),
+	},
+	{
+		Subject: jsonld.BlankNode("b0"),
+		Predicate: jsonld.IRI("http://example.org/p"),
+		Object: jsonld.Literal{Value: "a \"b\"\né", Datatype: rdfLangString, Language: "en-US"},
+		Graph: jsonld.IRI("http://example.org/g"),
+	},
+	{
+		Subject: jsonld.BlankNode("b0.1"),
+		Predicate: jsonld.IRI("http://example.org/p"),
+		Object: jsonld.Literal{Value: "42", Datatype: "http://www.w3.org/2001/XMLSchema#integer"},
+		Graph: jsonld.BlankNode("g"),
+	},
+}
+
+const testNQuadsCanonical = `<http://example.org/s> <http://example.org/p> <http://example.org/o> .
+_:b0 <http://example.org/p> "a \"b\"\né"@en-US <http://example.org/g> .
+_:b0.1 <http://example.org/p> "42"^^<http://www.w3.org/2001/XMLSchema#integer> _:g .
+`
+
+func TestUnmarshal(t *testing.T) {
+	quads, err := Unmarshal([]byte(testNQuads))
+	if err != nil {
+		t.Fatalf("Unmarshal() = %v", err)
+	}
+	if !reflect.DeepEqual(quads, testQuads) {
+		t.Errorf("Unmarshal() = %#v, want %#v", quads, testQuads)
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	b, err := Marshal(testQuads)
+	if err != nil {
+		t.Fatalf("Marshal() = %v", err)
+	}
+	if string(b) != testNQuadsCanonical {
+		t.Errorf("Marshal() = \n%v\nwant \n%v", string(b), testNQuadsCanonical)
+	}
+}
+
+func TestUnmarshal_error(t *testing.T) {
+	tests := []struct{
+		in string
+		line, column int
+	}{
+		{"<http://example.org/s> <http://example.org/p> <o> .", 1, 47},
+		{"\n<http://example.org/s> \"p\" <http://example.org/o> .", 2, 24},
+		{"<http://example.org/s> <http://example.org/p> \"o\\q\" .", 1, 49},
+		{"<http://example.org/s> <http://example.org/p> \"o\"", 1, 50},
+		{"_:s <http://example.org/p> \"o\"@ .", 1, 32},
+	}
+	for _, test := range tests {
+		_, err := Unmarshal([]byte(test.in))
+		synErr, ok := err.(*SyntaxError)
+		if !ok {
+			t.Errorf("Unmarshal(%q) = %v, want a syntax error", test.in, err)
+			continue
+		}
+		if synErr.Line != test.line || synErr.Column != test.column {
+			t.Errorf("Unmarshal(%q) = %v, want line %v, column %v", test.in, err, test.line, test.column)
+		}
+	}
+}
@@ -2,6 +2,27 @@ package jsonld
 
 type Props map[string][]interface{}
 
+// LangString is a string tagged with a language, such as "en" or "fr-CA".
+// Direction is the base direction of the string: "ltr", "rtl", or empty if
+// unspecified.
+type LangString struct {
+	Value string
+	Language string
+	Direction string
+}
+
+// Indexed is a value with an index, such as the values of an index map. The
+// index doesn't carry any meaning in the data model.
+type Indexed struct {
+	Index string
+	Value interface{}
+}
+
+// List is an ordered list of values. Unlike the values of a property, the
+// order of the items of a list is significant. A list is stored as a single
+// value in Props.
+type List []interface{}
+
 func (p Props) Get(k string) interface{} {
 	v, ok := p[k]
 	if !ok || len(v) == 0 {
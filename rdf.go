@@ -0,0 +1,685 @@
+package jsonld
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	rdfFirst = nsRDFS + "first"
+	rdfRest = nsRDFS + "rest"
+	rdfNil = nsRDFS + "nil"
+	rdfLangString = nsRDFS + "langString"
+	rdfList = nsRDFS + "List"
+	rdfValue = nsRDFS + "value"
+	rdfLanguage = nsRDFS + "language"
+	rdfDirection = nsRDFS + "direction"
+	rdfJSON = nsRDFS + "JSON"
+)
+
+const nsI18N = "https://www.w3.org/ns/i18n#"
+
+// RDFTerm is an RDF term: an IRI, a blank node or a literal.
+type RDFTerm interface {
+	// String returns the N-Quads representation of the term.
+	String() string
+
+	isRDFTerm()
+}
+
+// IRI is an RDF IRI.
+type IRI string
+
+func (iri IRI) String() string {
+	return "<" + escapeIRI(string(iri)) + ">"
+}
+
+func (IRI) isRDFTerm() {}
+
+// BlankNode is an RDF blank node. Its value is the blank node label, without
+// the "_:" prefix.
+type BlankNode string
+
+func (bn BlankNode) String() string {
+	return "_:" + string(bn)
+}
+
+func (BlankNode) isRDFTerm() {}
+
+// Literal is an RDF literal.
+type Literal struct {
+	Value string
+	// Datatype is the datatype IRI. If empty, xsd:string is assumed for
+	// literals without a language tag, and rdf:langString for literals with a
+	// language tag.
+	Datatype IRI
+	Language string
+}
+
+func (lit Literal) String() string {
+	s := `"` + escapeLiteral(lit.Value) + `"`
+	if lit.Language != "" {
+		return s + "@" + lit.Language
+	}
+	if lit.Datatype != "" && lit.Datatype != typeString {
+		return s + "^^" + lit.Datatype.String()
+	}
+	return s
+}
+
+func (Literal) isRDFTerm() {}
+
+// escapeLiteral escapes a string in the canonical N-Quads form.
+func escapeLiteral(s string) string {
+	var sb strings.Builder
+	for _, c := range s {
+		switch c {
+		case '\\':
+			sb.WriteString(`\\`)
+		case '"':
+			sb.WriteString(`\"`)
+		case '\b':
+			sb.WriteString(`\b`)
+		case '\t':
+			sb.WriteString(`\t`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\f':
+			sb.WriteString(`\f`)
+		case '\r':
+			sb.WriteString(`\r`)
+		default:
+			if c < 0x20 || c == 0x7F {
+				fmt.Fprintf(&sb, `\u%04X`, c)
+			} else {
+				sb.WriteRune(c)
+			}
+		}
+	}
+	return sb.String()
+}
+
+// escapeIRI escapes the characters which aren't allowed in N-Quads IRIs with
+// UCHAR escape sequences.
+func escapeIRI(s string) string {
+	var sb strings.Builder
+	for _, c := range s {
+		if c <= 0x20 || c == 0x7F || strings.ContainsRune("<>\"{}|^`\\", c) {
+			fmt.Fprintf(&sb, `\u%04X`, c)
+		} else {
+			sb.WriteRune(c)
+		}
+	}
+	return sb.String()
+}
+
+// Quad is an RDF statement.
+type Quad struct {
+	Subject, Predicate, Object RDFTerm
+	// Graph is the graph name, or nil for the default graph.
+	Graph RDFTerm
+}
+
+// String returns the N-Quads representation of the statement, without the
+// trailing newline.
+func (q Quad) String() string {
+	s := q.Subject.String() + " " + q.Predicate.String() + " " + q.Object.String()
+	if q.Graph != nil {
+		s += " " + q.Graph.String()
+	}
+	return s + " ."
+}
+
+// rdfResource converts a node identifier to an RDF term. It returns nil if the
+// identifier is a relative IRI.
+func rdfResource(id string) RDFTerm {
+	if isBlankNodeID(id) {
+		return BlankNode(strings.TrimPrefix(id, "_:"))
+	}
+	if !IsAbsoluteIRI(id) {
+		return nil
+	}
+	return IRI(id)
+}
+
+// ToRDF converts a JSON-LD document to an RDF dataset, as defined in
+// https://www.w3.org/TR/json-ld11-api/#deserialize-json-ld-to-rdf-algorithm.
+//
+// The input document must be a value decoded by the encoding/json package.
+// Blank nodes are relabeled.
+func ToRDF(input interface{}, opts *Options) ([]Quad, error) {
+	p := newProcessor(opts)
+	switch p.opts.RDFDirection {
+	case "", "i18n-datatype", "compound-literal":
+	default:
+		return nil, errorf("invalid rdfDirection", "%q", p.opts.RDFDirection)
+	}
+
+	expanded, err := p.expandDocument(input)
+	if err != nil {
+		return nil, err
+	}
+
+	nm := newNodeMap()
+	if err := nm.generate(expanded, "@default", "", "", nil); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(nm.graphs))
+	for name := range nm.graphs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var quads []Quad
+	for _, name := range names {
+		var graph RDFTerm
+		if name != "@default" {
+			if graph = rdfResource(name); graph == nil {
+				continue
+			}
+		}
+
+		g := nm.graphs[name]
+		for _, id := range sortedNodeIDs(g) {
+			subject := rdfResource(id)
+			if subject == nil {
+				continue
+			}
+
+			node := g[id]
+			for _, k := range sortedKeys(node) {
+				if k == "@type" {
+					for _, t := range toArray(node[k]) {
+						s, _ := t.(string)
+						if object := rdfResource(s); object != nil {
+							quads = append(quads, Quad{subject, IRI(propType), object, graph})
+						}
+					}
+					continue
+				} else if isKeyword(k) {
+					continue
+				}
+
+				predicate := rdfResource(k)
+				if predicate == nil {
+					continue
+				}
+				if _, ok := predicate.(BlankNode); ok && !p.opts.ProduceGeneralizedRDF {
+					continue
+				}
+
+				for _, item := range toArray(node[k]) {
+					var listQuads []Quad
+					object := p.objectToRDF(nm.issuer, item, &listQuads)
+					if object != nil {
+						quads = append(quads, Quad{subject, predicate, object, graph})
+					}
+					for _, q := range listQuads {
+						q.Graph = graph
+						quads = append(quads, q)
+					}
+				}
+			}
+		}
+	}
+
+	return quads, nil
+}
+
+// objectToRDF converts an expanded value to an RDF term. Statements describing
+// lists and compound literals are appended to listQuads.
+func (p *processor) objectToRDF(issuer *IdentifierIssuer, item interface{}, listQuads *[]Quad) RDFTerm {
+	m, ok := item.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	if l, ok := m["@list"]; ok {
+		return p.listToRDF(issuer, toArray(l), listQuads)
+	}
+
+	v, ok := m["@value"]
+	if !ok {
+		id, _ := m["@id"].(string)
+		return rdfResource(id)
+	}
+
+	datatype, _ := m["@type"].(string)
+	if datatype == "@json" {
+		value, err := canonicalJSON(v)
+		if err != nil {
+			return nil
+		}
+		return Literal{Value: value, Datatype: rdfJSON}
+	}
+	if datatype != "" && !IsAbsoluteIRI(datatype) {
+		return nil
+	}
+	lang, _ := m["@language"].(string)
+	dir, _ := m["@direction"].(string)
+
+	var value string
+	switch v := v.(type) {
+	case bool:
+		value = strconv.FormatBool(v)
+		if datatype == "" {
+			datatype = typeBoolean
+		}
+	case float64:
+		if v != math.Trunc(v) || math.Abs(v) >= 1e21 || datatype == typeDouble {
+			value = formatDouble(v)
+			if datatype == "" {
+				datatype = typeDouble
+			}
+		} else {
+			value = strconv.FormatFloat(v, 'f', -1, 64)
+			if datatype == "" {
+				datatype = typeInteger
+			}
+		}
+	case int64:
+		value = strconv.FormatInt(v, 10)
+		if datatype == "" {
+			datatype = typeInteger
+		}
+	case string:
+		value = v
+		if datatype == "" && dir != "" {
+			switch p.opts.RDFDirection {
+			case "i18n-datatype":
+				return Literal{Value: value, Datatype: IRI(nsI18N + lang + "_" + dir)}
+			case "compound-literal":
+				return compoundLiteralToRDF(issuer, value, lang, dir, listQuads)
+			}
+		}
+		if datatype == "" {
+			if lang != "" {
+				datatype = rdfLangString
+			} else {
+				datatype = typeString
+			}
+		}
+	default:
+		return nil
+	}
+
+	return Literal{Value: value, Datatype: IRI(datatype), Language: lang}
+}
+
+// compoundLiteralToRDF converts a string with a base direction to a compound
+// literal: a blank node with rdf:value, rdf:language and rdf:direction
+// properties.
+func compoundLiteralToRDF(issuer *IdentifierIssuer, value, lang, dir string, listQuads *[]Quad) RDFTerm {
+	node := BlankNode(strings.TrimPrefix(issuer.Issue(""), "_:"))
+	*listQuads = append(*listQuads, Quad{Subject: node, Predicate: IRI(rdfValue), Object: Literal{Value: value, Datatype: typeString}})
+	if lang != "" {
+		*listQuads = append(*listQuads, Quad{Subject: node, Predicate: IRI(rdfLanguage), Object: Literal{Value: lang, Datatype: typeString}})
+	}
+	*listQuads = append(*listQuads, Quad{Subject: node, Predicate: IRI(rdfDirection), Object: Literal{Value: dir, Datatype: typeString}})
+	return node
+}
+
+// formatDouble formats a number in the canonical lexical form of xsd:double.
+func formatDouble(f float64) string {
+	s := strconv.FormatFloat(f, 'E', -1, 64)
+	i := strings.IndexByte(s, 'E')
+	mantissa, exp := s[:i], s[i+1:]
+	if !strings.Contains(mantissa, ".") {
+		mantissa += ".0"
+	}
+	e, _ := strconv.Atoi(exp)
+	return mantissa + "E" + strconv.Itoa(e)
+}
+
+// listToRDF converts a list to RDF collection statements, as defined in
+// https://www.w3.org/TR/json-ld11-api/#list-to-rdf-conversion. It returns the
+// head of the list.
+func (p *processor) listToRDF(issuer *IdentifierIssuer, list []interface{}, listQuads *[]Quad) RDFTerm {
+	if len(list) == 0 {
+		return IRI(rdfNil)
+	}
+
+	nodes := make([]RDFTerm, len(list))
+	for i := range list {
+		nodes[i] = BlankNode(strings.TrimPrefix(issuer.Issue(""), "_:"))
+	}
+
+	for i, item := range list {
+		subject := nodes[i]
+		if object := p.objectToRDF(issuer, item, listQuads); object != nil {
+			*listQuads = append(*listQuads, Quad{Subject: subject, Predicate: IRI(rdfFirst), Object: object})
+		}
+
+		var rest RDFTerm = IRI(rdfNil)
+		if i+1 < len(nodes) {
+			rest = nodes[i+1]
+		}
+		*listQuads = append(*listQuads, Quad{Subject: subject, Predicate: IRI(rdfRest), Object: rest})
+	}
+
+	return nodes[0]
+}
+
+// rdfTermID returns the node identifier of an IRI or a blank node. It returns
+// an empty string for literals.
+func rdfTermID(t RDFTerm) string {
+	switch t := t.(type) {
+	case IRI:
+		return string(t)
+	case BlankNode:
+		return "_:" + string(t)
+	default:
+		return ""
+	}
+}
+
+// rdfUsage records a reference to a node, used to convert RDF collections to
+// lists.
+type rdfUsage struct {
+	node map[string]interface{}
+	prop string
+	value map[string]interface{}
+}
+
+// FromRDF converts an RDF dataset to an expanded JSON-LD document, as defined
+// in https://www.w3.org/TR/json-ld11-api/#serialize-rdf-as-json-ld-algorithm.
+//
+// Well-formed RDF collections are converted to lists. Named graphs are
+// represented as nodes with a @graph entry.
+func FromRDF(dataset []Quad, opts *Options) ([]interface{}, error) {
+	p := newProcessor(opts)
+	switch p.opts.RDFDirection {
+	case "", "i18n-datatype", "compound-literal":
+	default:
+		return nil, errorf("invalid rdfDirection", "%q", p.opts.RDFDirection)
+	}
+
+	defaultGraph := make(map[string]map[string]interface{})
+	graphs := map[string]map[string]map[string]interface{}{
+		"@default": defaultGraph,
+	}
+	nilUsages := make(map[string][]rdfUsage)
+	referencedOnce := make(map[string]*rdfUsage)
+	compoundLiterals := make(map[string]map[string]bool)
+
+	for _, q := range dataset {
+		name := "@default"
+		if q.Graph != nil {
+			if name = rdfTermID(q.Graph); name == "" {
+				continue
+			}
+		}
+		subject, predicate := rdfTermID(q.Subject), rdfTermID(q.Predicate)
+		if subject == "" || predicate == "" {
+			continue
+		}
+
+		g, ok := graphs[name]
+		if !ok {
+			g = make(map[string]map[string]interface{})
+			graphs[name] = g
+		}
+		if name != "@default" {
+			if _, ok := defaultGraph[name]; !ok {
+				defaultGraph[name] = map[string]interface{}{"@id": name}
+			}
+		}
+
+		node, ok := g[subject]
+		if !ok {
+			node = map[string]interface{}{"@id": subject}
+			g[subject] = node
+		}
+
+		object := rdfTermID(q.Object)
+		if object != "" {
+			if _, ok := g[object]; !ok {
+				g[object] = map[string]interface{}{"@id": object}
+			}
+		}
+
+		if predicate == propType && object != "" && !p.opts.UseRDFType {
+			addUniqueValue(node, "@type", object)
+			continue
+		}
+
+		value, err := p.rdfToObject(q.Object)
+		if err != nil {
+			return nil, err
+		}
+		values, _ := node[predicate].([]interface{})
+		if !containsValue(values, value) {
+			node[predicate] = append(values, value)
+		}
+
+		if object == rdfNil {
+			nilUsages[name] = append(nilUsages[name], rdfUsage{node, predicate, value})
+		} else if _, ok := referencedOnce[object]; ok {
+			referencedOnce[object] = nil
+		} else if _, ok := q.Object.(BlankNode); ok {
+			referencedOnce[object] = &rdfUsage{node, predicate, value}
+		}
+
+		if p.opts.RDFDirection == "compound-literal" && predicate == rdfDirection {
+			if compoundLiterals[name] == nil {
+				compoundLiterals[name] = make(map[string]bool)
+			}
+			compoundLiterals[name][subject] = true
+		}
+	}
+
+	for name, g := range graphs {
+		for cl := range compoundLiterals[name] {
+			convertCompoundLiteral(g, cl)
+		}
+
+		for _, usage := range nilUsages[name] {
+			node, prop, head := usage.node, usage.prop, usage.value
+			var list []interface{}
+			var listNodes []string
+			for prop == rdfRest && isWellFormedListNode(node, referencedOnce) {
+				list = append(list, node[rdfFirst].([]interface{})[0])
+				id := node["@id"].(string)
+				listNodes = append(listNodes, id)
+
+				u := referencedOnce[id]
+				node, prop, head = u.node, u.prop, u.value
+				if !isBlankNodeID(node["@id"].(string)) {
+					break
+				}
+			}
+
+			delete(head, "@id")
+			for i, j := 0, len(list)-1; i < j; i, j = i+1, j-1 {
+				list[i], list[j] = list[j], list[i]
+			}
+			if list == nil {
+				list = []interface{}{}
+			}
+			head["@list"] = list
+			for _, id := range listNodes {
+				delete(g, id)
+			}
+		}
+	}
+
+	var result []interface{}
+	for _, id := range sortedNodeIDs(defaultGraph) {
+		node := defaultGraph[id]
+		if g, ok := graphs[id]; ok && id != "@default" {
+			var nodes []interface{}
+			for _, id := range sortedNodeIDs(g) {
+				if n := g[id]; len(n) > 1 {
+					nodes = append(nodes, n)
+				}
+			}
+			if nodes == nil {
+				nodes = []interface{}{}
+			}
+			node["@graph"] = nodes
+		}
+		if len(node) > 1 {
+			result = append(result, node)
+		}
+	}
+	if result == nil {
+		result = []interface{}{}
+	}
+	return result, nil
+}
+
+// ResourcesFromRDF converts an RDF dataset to resources. Only the nodes of the
+// default graph are returned. The resources can be stored in Go values with
+// Decoder.DecodeResource.
+func ResourcesFromRDF(dataset []Quad, opts *Options) ([]*Resource, error) {
+	expanded, err := FromRDF(dataset, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var d Decoder
+	resources := make([]*Resource, 0, len(expanded))
+	for _, v := range expanded {
+		m, _ := v.(map[string]interface{})
+		r, err := d.parseResource(m)
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, r)
+	}
+	return resources, nil
+}
+
+// isWellFormedListNode checks whether a node is a blank node referenced once,
+// with exactly one rdf:first and one rdf:rest value.
+func isWellFormedListNode(node map[string]interface{}, referencedOnce map[string]*rdfUsage) bool {
+	id, _ := node["@id"].(string)
+	if !isBlankNodeID(id) || referencedOnce[id] == nil {
+		return false
+	}
+	for k, v := range node {
+		values, _ := v.([]interface{})
+		switch k {
+		case "@id":
+		case rdfFirst, rdfRest:
+			if len(values) != 1 {
+				return false
+			}
+		case "@type":
+			if len(values) != 1 || values[0] != rdfList {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	_, hasFirst := node[rdfFirst]
+	_, hasRest := node[rdfRest]
+	return hasFirst && hasRest
+}
+
+// convertCompoundLiteral replaces references to the compound literal node cl
+// with a value object, and removes the node from the graph.
+func convertCompoundLiteral(g map[string]map[string]interface{}, cl string) {
+	entry := g[cl]
+	value := map[string]interface{}{}
+	for k, prop := range map[string]string{
+		"@value": rdfValue,
+		"@language": rdfLanguage,
+		"@direction": rdfDirection,
+	} {
+		values, _ := entry[prop].([]interface{})
+		if m, ok := firstMap(values); ok {
+			if v, ok := m["@value"]; ok {
+				value[k] = v
+			}
+		}
+	}
+
+	for _, node := range g {
+		for k, v := range node {
+			values, ok := v.([]interface{})
+			if !ok {
+				continue
+			}
+			for i, item := range values {
+				if m, ok := item.(map[string]interface{}); ok && m["@id"] == cl {
+					values[i] = value
+				}
+			}
+			node[k] = values
+		}
+	}
+	delete(g, cl)
+}
+
+var (
+	integerRegexp = regexp.MustCompile(`^[+-]?[0-9]+$`)
+	doubleRegexp = regexp.MustCompile(`^[+-]?([0-9]+(\.[0-9]*)?|\.[0-9]+)([Ee][+-]?[0-9]+)?$`)
+)
+
+// rdfToObject converts an RDF term to an expanded value, as defined in
+// https://www.w3.org/TR/json-ld11-api/#rdf-to-object-conversion.
+func (p *processor) rdfToObject(t RDFTerm) (map[string]interface{}, error) {
+	lit, ok := t.(Literal)
+	if !ok {
+		return map[string]interface{}{"@id": rdfTermID(t)}, nil
+	}
+
+	result := make(map[string]interface{})
+	var value interface{} = lit.Value
+	datatype := string(lit.Datatype)
+	if lit.Language != "" {
+		datatype = rdfLangString
+	} else if datatype == "" {
+		datatype = typeString
+	}
+
+	switch {
+	case datatype == typeString:
+		datatype = ""
+	case p.opts.UseNativeTypes && datatype == typeBoolean:
+		switch lit.Value {
+		case "true":
+			value, datatype = true, ""
+		case "false":
+			value, datatype = false, ""
+		}
+	case p.opts.UseNativeTypes && datatype == typeInteger && integerRegexp.MatchString(lit.Value),
+		p.opts.UseNativeTypes && datatype == typeDouble && doubleRegexp.MatchString(lit.Value):
+		if f, err := strconv.ParseFloat(lit.Value, 64); err == nil {
+			value, datatype = f, ""
+		}
+	case p.opts.RDFDirection == "i18n-datatype" && strings.HasPrefix(datatype, nsI18N):
+		tag := strings.TrimPrefix(datatype, nsI18N)
+		if i := strings.IndexByte(tag, '_'); i >= 0 {
+			if lang := tag[:i]; lang != "" {
+				result["@language"] = lang
+			}
+			if dir := tag[i+1:]; dir != "" {
+				result["@direction"] = dir
+			}
+		}
+		datatype = ""
+	case lit.Language != "":
+		result["@language"] = lit.Language
+		datatype = ""
+	case datatype == rdfJSON:
+		if err := json.Unmarshal([]byte(lit.Value), &value); err != nil {
+			return nil, errorf("invalid JSON literal", "%v", err)
+		}
+		datatype = "@json"
+	}
+
+	result["@value"] = value
+	if datatype != "" {
+		result["@type"] = datatype
+	}
+	return result, nil
+}
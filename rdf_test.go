@@ -0,0 +1,295 @@
+package jsonld
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var toRDFTests = []struct{
+	name string
+	in string
+	opts *Options
+	out string
+}{
+	{
+		name: "literals",
+		in: `{
+			"@context": {"@vocab": "http://example.org/"},
+			"@id": "http://example.org/s",
+			"@type": "Thing",
+			"bool": true,
+			"int": 42,
+			"double": 1.1,
+			"string": "a \"quoted\"\nline",
+			"lang": {"@value": "chat", "@language": "fr"},
+			"typed": {"@value": "2020-01-01", "@type": "http://www.w3.org/2001/XMLSchema#date"},
+			"relative": {"@id": "relative"}
+		}`,
+		out: `<http://example.org/s> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://example.org/Thing> .
+<http://example.org/s> <http://example.org/bool> "true"^^<http://www.w3.org/2001/XMLSchema#boolean> .
+<http://example.org/s> <http://example.org/double> "1.1E0"^^<http://www.w3.org/2001/XMLSchema#double> .
+<http://example.org/s> <http://example.org/int> "42"^^<http://www.w3.org/2001/XMLSchema#integer> .
+<http://example.org/s> <http://example.org/lang> "chat"@fr .
+<http://example.org/s> <http://example.org/string> "a \"quoted\"\nline" .
+<http://example.org/s> <http://example.org/typed> "2020-01-01"^^<http://www.w3.org/2001/XMLSchema#date> .
+`,
+	},
+	{
+		name: "list and named graph",
+		in: `{
+			"@context": {"@vocab": "http://example.org/"},
+			"@id": "http://example.org/g",
+			"@graph": {
+				"@id": "_:x",
+				"list": {"@list": ["a", {"@id": "_:y"}]},
+				"empty": {"@list": []}
+			}
+		}`,
+		out: `_:b0 <http://example.org/empty> <http://www.w3.org/1999/02/22-rdf-syntax-ns#nil> <http://example.org/g> .
+_:b0 <http://example.org/list> _:b2 <http://example.org/g> .
+_:b2 <http://www.w3.org/1999/02/22-rdf-syntax-ns#first> "a" <http://example.org/g> .
+_:b2 <http://www.w3.org/1999/02/22-rdf-syntax-ns#rest> _:b3 <http://example.org/g> .
+_:b3 <http://www.w3.org/1999/02/22-rdf-syntax-ns#first> _:b1 <http://example.org/g> .
+_:b3 <http://www.w3.org/1999/02/22-rdf-syntax-ns#rest> <http://www.w3.org/1999/02/22-rdf-syntax-ns#nil> <http://example.org/g> .
+`,
+	},
+	{
+		name: "JSON literal",
+		in: exampleJSONLiteral,
+		out: `<http://example.org/s> <http://example.org/config> "{\"a\":null,\"b\":[1,1e+21,0.5],\"c\":\"x\"}"^^<http://www.w3.org/1999/02/22-rdf-syntax-ns#JSON> .
+`,
+	},
+	{
+		name: "direction dropped",
+		in: exampleDirectionRDF,
+		out: `<http://example.org/s> <http://example.org/title> "كتاب"@ar .
+`,
+	},
+	{
+		name: "i18n datatype",
+		in: exampleDirectionRDF,
+		opts: &Options{RDFDirection: "i18n-datatype"},
+		out: `<http://example.org/s> <http://example.org/title> "كتاب"^^<https://www.w3.org/ns/i18n#ar_rtl> .
+`,
+	},
+	{
+		name: "compound literal",
+		in: exampleDirectionRDF,
+		opts: &Options{RDFDirection: "compound-literal"},
+		out: `<http://example.org/s> <http://example.org/title> _:b0 .
+_:b0 <http://www.w3.org/1999/02/22-rdf-syntax-ns#value> "كتاب" .
+_:b0 <http://www.w3.org/1999/02/22-rdf-syntax-ns#language> "ar" .
+_:b0 <http://www.w3.org/1999/02/22-rdf-syntax-ns#direction> "rtl" .
+`,
+	},
+}
+
+const exampleJSONLiteral = `{
+	"@context": {
+		"@vocab": "http://example.org/",
+		"config": {"@id": "http://example.org/config", "@type": "@json"}
+	},
+	"@id": "http://example.org/s",
+	"config": {"b": [1, 1e21, 0.5], "a": null, "c": "x"}
+}`
+
+const exampleDirectionRDF = `{
+	"@context": {"@vocab": "http://example.org/", "@language": "ar", "@direction": "rtl"},
+	"@id": "http://example.org/s",
+	"title": "كتاب"
+}`
+
+func TestToRDF(t *testing.T) {
+	for _, test := range toRDFTests {
+		var in interface{}
+		if err := json.Unmarshal([]byte(test.in), &in); err != nil {
+			t.Fatalf("%v: json.Unmarshal() = %v", test.name, err)
+		}
+
+		quads, err := ToRDF(in, test.opts)
+		if err != nil {
+			t.Errorf("%v: ToRDF() = %v", test.name, err)
+			continue
+		}
+
+		var sb strings.Builder
+		for _, q := range quads {
+			sb.WriteString(q.String() + "\n")
+		}
+		if got := sb.String(); got != test.out {
+			t.Errorf("%v: ToRDF() = \n%v\nwant \n%v", test.name, got, test.out)
+		}
+	}
+}
+
+func TestFormatDouble(t *testing.T) {
+	tests := map[float64]string{
+		1.1: "1.1E0",
+		10: "1.0E1",
+		-0.00053: "-5.3E-4",
+		1e21: "1.0E21",
+	}
+	for f, want := range tests {
+		if got := formatDouble(f); got != want {
+			t.Errorf("formatDouble(%v) = %v, want %v", f, got, want)
+		}
+	}
+}
+
+func TestIRI_String(t *testing.T) {
+	tests := map[IRI]string{
+		"http://example.org/a": "<http://example.org/a>",
+		"http://example.org/a b": `<http://example.org/a\u0020b>`,
+		"http://example.org/a>b": `<http://example.org/a\u003Eb>`,
+		"http://example.org/a\\b": `<http://example.org/a\u005Cb>`,
+		"http://example.org/a\nb": `<http://example.org/a\u000Ab>`,
+		"http://example.org/é": "<http://example.org/é>",
+	}
+	for iri, want := range tests {
+		if got := iri.String(); got != want {
+			t.Errorf("IRI(%q).String() = %v, want %v", string(iri), got, want)
+		}
+	}
+}
+
+const (
+	exampleS = IRI("http://example.org/s")
+	exampleP = IRI("http://example.org/p")
+)
+
+var fromRDFTests = []struct{
+	name string
+	in []Quad
+	opts *Options
+	out string
+}{
+	{
+		name: "list and type",
+		in: []Quad{
+			{exampleS, IRI(propType), IRI("http://example.org/Thing"), nil},
+			{exampleS, exampleP, BlankNode("l0"), nil},
+			{BlankNode("l0"), IRI(rdfFirst), Literal{Value: "a"}, nil},
+			{BlankNode("l0"), IRI(rdfRest), BlankNode("l1"), nil},
+			{BlankNode("l1"), IRI(rdfFirst), Literal{Value: "1", Datatype: typeInteger}, nil},
+			{BlankNode("l1"), IRI(rdfRest), IRI(rdfNil), nil},
+		},
+		out: `[{
+			"@id": "http://example.org/s",
+			"@type": ["http://example.org/Thing"],
+			"http://example.org/p": [{"@list": [
+				{"@value": "a"},
+				{"@value": "1", "@type": "http://www.w3.org/2001/XMLSchema#integer"}
+			]}]
+		}]`,
+	},
+	{
+		name: "native types and rdf:type",
+		in: []Quad{
+			{exampleS, IRI(propType), IRI("http://example.org/Thing"), nil},
+			{exampleS, exampleP, Literal{Value: "true", Datatype: typeBoolean}, nil},
+			{exampleS, exampleP, Literal{Value: "42", Datatype: typeInteger}, nil},
+			{exampleS, exampleP, Literal{Value: "chat", Language: "fr"}, nil},
+			{exampleS, exampleP, Literal{Value: "x", Datatype: typeDouble}, nil},
+		},
+		opts: &Options{UseNativeTypes: true, UseRDFType: true},
+		out: `[{
+			"@id": "http://example.org/s",
+			"http://www.w3.org/1999/02/22-rdf-syntax-ns#type": [{"@id": "http://example.org/Thing"}],
+			"http://example.org/p": [
+				{"@value": true},
+				{"@value": 42},
+				{"@value": "chat", "@language": "fr"},
+				{"@value": "x", "@type": "http://www.w3.org/2001/XMLSchema#double"}
+			]
+		}]`,
+	},
+	{
+		name: "named graph and i18n datatype",
+		in: []Quad{
+			{exampleS, exampleP, Literal{Value: "abc", Datatype: nsI18N + "ar-EG_rtl"}, IRI("http://example.org/g")},
+		},
+		opts: &Options{RDFDirection: "i18n-datatype"},
+		out: `[{
+			"@id": "http://example.org/g",
+			"@graph": [{
+				"@id": "http://example.org/s",
+				"http://example.org/p": [{"@value": "abc", "@language": "ar-EG", "@direction": "rtl"}]
+			}]
+		}]`,
+	},
+	{
+		name: "JSON literal",
+		in: []Quad{
+			{exampleS, exampleP, Literal{Value: `{"a":[1,true]}`, Datatype: rdfJSON}, nil},
+		},
+		out: `[{
+			"@id": "http://example.org/s",
+			"http://example.org/p": [{"@value": {"a": [1, true]}, "@type": "@json"}]
+		}]`,
+	},
+	{
+		name: "compound literal",
+		in: []Quad{
+			{exampleS, exampleP, BlankNode("cl"), nil},
+			{BlankNode("cl"), IRI(rdfValue), Literal{Value: "abc"}, nil},
+			{BlankNode("cl"), IRI(rdfLanguage), Literal{Value: "ar-EG"}, nil},
+			{BlankNode("cl"), IRI(rdfDirection), Literal{Value: "rtl"}, nil},
+		},
+		opts: &Options{RDFDirection: "compound-literal"},
+		out: `[{
+			"@id": "http://example.org/s",
+			"http://example.org/p": [{"@value": "abc", "@language": "ar-EG", "@direction": "rtl"}]
+		}]`,
+	},
+}
+
+func TestFromRDF(t *testing.T) {
+	for _, test := range fromRDFTests {
+		var want interface{}
+		if err := json.Unmarshal([]byte(test.out), &want); err != nil {
+			t.Fatalf("%v: json.Unmarshal() = %v", test.name, err)
+		}
+
+		out, err := FromRDF(test.in, test.opts)
+		if err != nil {
+			t.Errorf("%v: FromRDF() = %v", test.name, err)
+			continue
+		}
+
+		got := roundTripJSON(t, out)
+		if !reflect.DeepEqual(got, want) {
+			b, _ := json.Marshal(out)
+			t.Errorf("%v: FromRDF() = %v, want %v", test.name, string(b), test.out)
+		}
+	}
+}
+
+func TestResourcesFromRDF(t *testing.T) {
+	dataset := []Quad{
+		{IRI("http://manu.sporny.org/"), IRI("http://schema.org/name"), Literal{Value: "Manu Sporny"}, nil},
+		{IRI("http://manu.sporny.org/"), IRI("http://schema.org/url"), IRI("http://manu.sporny.org/"), nil},
+	}
+
+	resources, err := ResourcesFromRDF(dataset, nil)
+	if err != nil {
+		t.Fatalf("ResourcesFromRDF() = %v", err)
+	}
+	if len(resources) != 1 {
+		t.Fatalf("ResourcesFromRDF() returned %v resources, want 1", len(resources))
+	}
+
+	var p person
+	if err := new(Decoder).DecodeResource(resources[0], &p); err != nil {
+		t.Fatalf("DecodeResource() = %v", err)
+	}
+	want := person{
+		ID: "http://manu.sporny.org/",
+		Name: "Manu Sporny",
+		URL: &Resource{ID: "http://manu.sporny.org/"},
+	}
+	if !reflect.DeepEqual(p, want) {
+		t.Errorf("DecodeResource() = %#v, want %#v", p, want)
+	}
+}
@@ -0,0 +1,370 @@
+// Package rdfc implements the RDF Dataset Canonicalization algorithm
+// (RDFC-1.0, formerly known as URDNA2015), as defined in
+// https://www.w3.org/TR/rdf-canon/.
+package rdfc
+
+import (
+	"crypto"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/emersion/go-jsonld"
+	"github.com/emersion/go-jsonld/nquads"
+)
+
+// DefaultWorkLimit is the default maximum number of calls to the Hash N-Degree
+// Quads algorithm.
+const DefaultWorkLimit = 10000
+
+// ErrWorkLimitExceeded is returned when canonicalizing a dataset requires too
+// much work, which can happen with specially crafted datasets.
+var ErrWorkLimitExceeded = errors.New("rdfc: work limit exceeded")
+
+// Options contains options for the canonicalization algorithm.
+type Options struct {
+	// Hash is the hash algorithm: crypto.SHA256 (the default) or
+	// crypto.SHA384.
+	Hash crypto.Hash
+	// WorkLimit is the maximum number of calls to the Hash N-Degree Quads
+	// algorithm. If zero, DefaultWorkLimit is used. If negative, there is no
+	// limit.
+	WorkLimit int
+}
+
+// Canonicalize relabels the blank nodes of a dataset with canonical labels
+// ("c14n0", "c14n1", and so on). The returned quads are sorted in code point
+// order of their N-Quads representation, and duplicates are removed.
+func Canonicalize(dataset []jsonld.Quad, opts *Options) ([]jsonld.Quad, error) {
+	if opts == nil {
+		opts = new(Options)
+	}
+	h := opts.Hash
+	if h == 0 {
+		h = crypto.SHA256
+	}
+	if h != crypto.SHA256 && h != crypto.SHA384 {
+		return nil, fmt.Errorf("rdfc: unsupported hash algorithm %v", h)
+	}
+	limit := opts.WorkLimit
+	if limit == 0 {
+		limit = DefaultWorkLimit
+	}
+
+	s := &state{
+		hash: h,
+		workLimit: limit,
+		blankNodeToQuads: make(map[string][]*jsonld.Quad),
+		canonicalIssuer: jsonld.NewIdentifierIssuer("c14n"),
+	}
+	return s.canonicalize(dataset)
+}
+
+// NQuads returns the canonical N-Quads representation of a dataset.
+func NQuads(dataset []jsonld.Quad, opts *Options) ([]byte, error) {
+	quads, err := Canonicalize(dataset, opts)
+	if err != nil {
+		return nil, err
+	}
+	return nquads.Marshal(quads)
+}
+
+// Hash returns the hash of the canonical N-Quads representation of a dataset.
+func Hash(dataset []jsonld.Quad, opts *Options) ([]byte, error) {
+	b, err := NQuads(dataset, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	h := crypto.SHA256
+	if opts != nil && opts.Hash != 0 {
+		h = opts.Hash
+	}
+	hh := h.New()
+	hh.Write(b)
+	return hh.Sum(nil), nil
+}
+
+type state struct {
+	hash crypto.Hash
+	workLimit int
+	work int
+
+	blankNodeToQuads map[string][]*jsonld.Quad
+	canonicalIssuer *jsonld.IdentifierIssuer
+}
+
+func (s *state) hashString(data string) string {
+	h := s.hash.New()
+	h.Write([]byte(data))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// blankNodes returns the blank node labels of the components of a quad, in
+// order: subject, object, graph.
+func blankNodes(q *jsonld.Quad) [3]jsonld.BlankNode {
+	var l [3]jsonld.BlankNode
+	for i, t := range []jsonld.RDFTerm{q.Subject, q.Object, q.Graph} {
+		if bn, ok := t.(jsonld.BlankNode); ok {
+			l[i] = bn
+		}
+	}
+	return l
+}
+
+var positions = [3]string{"s", "o", "g"}
+
+func (s *state) canonicalize(dataset []jsonld.Quad) ([]jsonld.Quad, error) {
+	seen := make(map[string]bool)
+	var quads []*jsonld.Quad
+	for i := range dataset {
+		q := &dataset[i]
+		if q.Subject == nil || q.Predicate == nil || q.Object == nil {
+			return nil, errors.New("rdfc: incomplete statement")
+		}
+		k := q.String()
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		quads = append(quads, q)
+
+		var added [3]jsonld.BlankNode
+		for i, bn := range blankNodes(q) {
+			if bn == "" || bn == added[0] || bn == added[1] {
+				continue
+			}
+			added[i] = bn
+			s.blankNodeToQuads[string(bn)] = append(s.blankNodeToQuads[string(bn)], q)
+		}
+	}
+
+	ids := make([]string, 0, len(s.blankNodeToQuads))
+	for id := range s.blankNodeToQuads {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	hashToBlankNodes := make(map[string][]string)
+	for _, id := range ids {
+		h := s.hashFirstDegreeQuads(id)
+		hashToBlankNodes[h] = append(hashToBlankNodes[h], id)
+	}
+
+	hashes := make([]string, 0, len(hashToBlankNodes))
+	for h := range hashToBlankNodes {
+		hashes = append(hashes, h)
+	}
+	sort.Strings(hashes)
+
+	var nonUnique []string
+	for _, h := range hashes {
+		if l := hashToBlankNodes[h]; len(l) == 1 {
+			s.canonicalIssuer.Issue(l[0])
+		} else {
+			nonUnique = append(nonUnique, h)
+		}
+	}
+
+	for _, h := range nonUnique {
+		var results []ndegreeResult
+		for _, id := range hashToBlankNodes[h] {
+			if _, ok := s.canonicalIssuer.Issued(id); ok {
+				continue
+			}
+			iss := jsonld.NewIdentifierIssuer("b")
+			iss.Issue(id)
+			result, err := s.hashNDegreeQuads(id, iss)
+			if err != nil {
+				return nil, err
+			}
+			results = append(results, result)
+		}
+		sort.SliceStable(results, func(i, j int) bool {
+			return results[i].hash < results[j].hash
+		})
+		for _, result := range results {
+			for _, id := range result.issuer.Identifiers() {
+				s.canonicalIssuer.Issue(id)
+			}
+		}
+	}
+
+	relabel := func(t jsonld.RDFTerm) jsonld.RDFTerm {
+		if bn, ok := t.(jsonld.BlankNode); ok {
+			id, _ := s.canonicalIssuer.Issued(string(bn))
+			return jsonld.BlankNode(id)
+		}
+		return t
+	}
+	out := make([]jsonld.Quad, len(quads))
+	for i, q := range quads {
+		out[i] = jsonld.Quad{
+			Subject: relabel(q.Subject),
+			Predicate: q.Predicate,
+			Object: relabel(q.Object),
+			Graph: relabel(q.Graph),
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].String() < out[j].String()
+	})
+	return out, nil
+}
+
+// hashFirstDegreeQuads implements
+// https://www.w3.org/TR/rdf-canon/#hash-1d-quads.
+func (s *state) hashFirstDegreeQuads(id string) string {
+	var lines []string
+	for _, q := range s.blankNodeToQuads[id] {
+		replace := func(t jsonld.RDFTerm) jsonld.RDFTerm {
+			if bn, ok := t.(jsonld.BlankNode); ok {
+				if string(bn) == id {
+					return jsonld.BlankNode("a")
+				}
+				return jsonld.BlankNode("z")
+			}
+			return t
+		}
+		q := jsonld.Quad{
+			Subject: replace(q.Subject),
+			Predicate: q.Predicate,
+			Object: replace(q.Object),
+			Graph: replace(q.Graph),
+		}
+		lines = append(lines, q.String()+"\n")
+	}
+	sort.Strings(lines)
+	return s.hashString(strings.Join(lines, ""))
+}
+
+// hashRelatedBlankNode implements
+// https://www.w3.org/TR/rdf-canon/#hash-related-blank-node.
+func (s *state) hashRelatedBlankNode(related string, q *jsonld.Quad, iss *jsonld.IdentifierIssuer, position string) string {
+	var id string
+	if issued, ok := s.canonicalIssuer.Issued(related); ok {
+		id = "_:" + issued
+	} else if issued, ok := iss.Issued(related); ok {
+		id = "_:" + issued
+	} else {
+		id = s.hashFirstDegreeQuads(related)
+	}
+
+	input := position
+	if position != "g" {
+		input += q.Predicate.String()
+	}
+	return s.hashString(input + id)
+}
+
+type ndegreeResult struct {
+	hash string
+	issuer *jsonld.IdentifierIssuer
+}
+
+// hashNDegreeQuads implements
+// https://www.w3.org/TR/rdf-canon/#hash-nd-quads.
+func (s *state) hashNDegreeQuads(id string, iss *jsonld.IdentifierIssuer) (ndegreeResult, error) {
+	s.work++
+	if s.workLimit > 0 && s.work > s.workLimit {
+		return ndegreeResult{}, ErrWorkLimitExceeded
+	}
+
+	hashToRelated := make(map[string][]string)
+	for _, q := range s.blankNodeToQuads[id] {
+		for i, bn := range blankNodes(q) {
+			if bn == "" || string(bn) == id {
+				continue
+			}
+			h := s.hashRelatedBlankNode(string(bn), q, iss, positions[i])
+			hashToRelated[h] = append(hashToRelated[h], string(bn))
+		}
+	}
+
+	hashes := make([]string, 0, len(hashToRelated))
+	for h := range hashToRelated {
+		hashes = append(hashes, h)
+	}
+	sort.Strings(hashes)
+
+	var data strings.Builder
+	for _, h := range hashes {
+		data.WriteString(h)
+
+		var chosenPath string
+		var chosenIssuer *jsonld.IdentifierIssuer
+		err := permute(hashToRelated[h], func(perm []string) error {
+			issCopy := iss.Clone()
+			var path strings.Builder
+			var recursion []string
+			worse := func() bool {
+				return chosenPath != "" && path.Len() >= len(chosenPath) && path.String() > chosenPath
+			}
+
+			for _, related := range perm {
+				if issued, ok := s.canonicalIssuer.Issued(related); ok {
+					path.WriteString("_:" + issued)
+				} else {
+					if _, ok := issCopy.Issued(related); !ok {
+						recursion = append(recursion, related)
+					}
+					path.WriteString("_:" + issCopy.Issue(related))
+				}
+				if worse() {
+					return nil
+				}
+			}
+
+			for _, related := range recursion {
+				result, err := s.hashNDegreeQuads(related, issCopy)
+				if err != nil {
+					return err
+				}
+				path.WriteString("_:" + issCopy.Issue(related))
+				path.WriteString("<" + result.hash + ">")
+				issCopy = result.issuer
+				if worse() {
+					return nil
+				}
+			}
+
+			if chosenPath == "" || path.String() < chosenPath {
+				chosenPath = path.String()
+				chosenIssuer = issCopy
+			}
+			return nil
+		})
+		if err != nil {
+			return ndegreeResult{}, err
+		}
+
+		data.WriteString(chosenPath)
+		iss = chosenIssuer
+	}
+
+	return ndegreeResult{hash: s.hashString(data.String()), issuer: iss}, nil
+}
+
+// permute calls f with each permutation of l.
+func permute(l []string, f func([]string) error) error {
+	perm := append([]string(nil), l...)
+	var rec func(k int) error
+	rec = func(k int) error {
+		if k == len(perm) {
+			return f(perm)
+		}
+		for i := k; i < len(perm); i++ {
+			perm[k], perm[i] = perm[i], perm[k]
+			if err := rec(k + 1); err != nil {
+				return err
+			}
+			perm[k], perm[i] = perm[i], perm[k]
+		}
+		return nil
+	}
+	return rec(0)
+}
@@ -0,0 +1,112 @@
+package rdfc
+
+import (
+	"crypto"
+	"testing"
+
+	"github.com/emersion/go-jsonld"
+	"github.com/emersion/go-jsonld/nquads"
+)
+
+var canonicalizeTests = []struct{
+	name string
+	in string
+	out string
+}{
+	{
+		name: "unique hashes",
+		in: `<http://example.com/#p> <http://example.com/#q> _:e0 .
+<http://example.com/#p> <http://example.com/#r> _:e1 .
+_:e0 <http://example.com/#s> <http://example.com/#u> .
+_:e1 <http://example.com/#t> <http://example.com/#u> .
+`,
+		out: `<http://example.com/#p> <http://example.com/#q> _:c14n0 .
+<http://example.com/#p> <http://example.com/#r> _:c14n1 .
+_:c14n0 <http://example.com/#s> <http://example.com/#u> .
+_:c14n1 <http://example.com/#t> <http://example.com/#u> .
+`,
+	},
+	{
+		name: "shared hashes",
+		in: `_:e2 <http://example.com/#r> _:e3 .
+<http://example.com/#p> <http://example.com/#q> _:e1 .
+_:e1 <http://example.com/#p> _:e3 .
+<http://example.com/#p> <http://example.com/#q> _:e0 .
+_:e0 <http://example.com/#p> _:e2 .
+_:e0 <http://example.com/#p> _:e2 .
+`,
+		out: `<http://example.com/#p> <http://example.com/#q> _:c14n2 .
+<http://example.com/#p> <http://example.com/#q> _:c14n3 .
+_:c14n0 <http://example.com/#r> _:c14n1 .
+_:c14n2 <http://example.com/#p> _:c14n1 .
+_:c14n3 <http://example.com/#p> _:c14n0 .
+`,
+	},
+	{
+		name: "named graph",
+		in: `_:s <http://example.com/#p> "a\tb" _:g .
+`,
+		out: `_:c14n1 <http://example.com/#p> "a\tb" _:c14n0 .
+`,
+	},
+}
+
+func TestNQuads(t *testing.T) {
+	for _, test := range canonicalizeTests {
+		quads, err := nquads.Unmarshal([]byte(test.in))
+		if err != nil {
+			t.Fatalf("%v: nquads.Unmarshal() = %v", test.name, err)
+		}
+
+		b, err := NQuads(quads, nil)
+		if err != nil {
+			t.Errorf("%v: NQuads() = %v", test.name, err)
+		} else if string(b) != test.out {
+			t.Errorf("%v: NQuads() = \n%v\nwant \n%v", test.name, string(b), test.out)
+		}
+	}
+}
+
+func TestHash(t *testing.T) {
+	quads, err := nquads.Unmarshal([]byte(canonicalizeTests[1].in))
+	if err != nil {
+		t.Fatalf("nquads.Unmarshal() = %v", err)
+	}
+
+	h, err := Hash(quads, nil)
+	if err != nil {
+		t.Fatalf("Hash() = %v", err)
+	}
+	if len(h) != 32 {
+		t.Errorf("Hash() returned %v bytes, want 32", len(h))
+	}
+
+	h, err = Hash(quads, &Options{Hash: crypto.SHA384})
+	if err != nil {
+		t.Fatalf("Hash(SHA-384) = %v", err)
+	}
+	if len(h) != 48 {
+		t.Errorf("Hash(SHA-384) returned %v bytes, want 48", len(h))
+	}
+
+	if _, err := Hash(quads, &Options{Hash: crypto.MD5}); err == nil {
+		t.Errorf("Hash(MD5) = nil, want an error")
+	}
+}
+
+func TestCanonicalize_workLimit(t *testing.T) {
+	// A cycle of blank nodes where all nodes share the same first degree hash
+	var quads []jsonld.Quad
+	nodes := []jsonld.BlankNode{"a", "b", "c", "d", "e", "f"}
+	for i, bn := range nodes {
+		next := nodes[(i+1)%len(nodes)]
+		quads = append(quads, jsonld.Quad{Subject: bn, Predicate: jsonld.IRI("http://example.com/#p"), Object: next})
+	}
+
+	if _, err := Canonicalize(quads, &Options{WorkLimit: 2}); err != ErrWorkLimitExceeded {
+		t.Errorf("Canonicalize() = %v, want %v", err, ErrWorkLimitExceeded)
+	}
+	if _, err := Canonicalize(quads, nil); err != nil {
+		t.Errorf("Canonicalize() = %v", err)
+	}
+}
@@ -2,11 +2,24 @@ package jsonld
 
 import (
 	"reflect"
+	"strings"
 )
 
 type Resource struct {
 	ID string
 	Props Props
+	// Reverse contains the reverse properties of the resource: for each
+	// property, the resources which have this resource as a value.
+	Reverse Props
+	// Included contains side-loaded resources, listed in the @included entry
+	// of the node.
+	Included []*Resource
+}
+
+// IsBlank checks whether the resource is a blank node, ie. whether its ID is
+// empty or is a blank node identifier such as "_:b0".
+func (r *Resource) IsBlank() bool {
+	return r.ID == "" || isBlankNodeID(r.ID)
 }
 
 func typeField(ft reflect.StructField) (t string, ok bool) {
@@ -16,16 +29,91 @@ func typeField(ft reflect.StructField) (t string, ok bool) {
 	return "", false
 }
 
-func getFieldURI(ctx *Context, ft reflect.StructField) (uri string, ok bool) {
+// field describes how a struct field is mapped to a property.
+type field struct {
+	uri string
+	// reverse is set if the field is mapped to a reverse property, either with
+	// the "reverse" tag option or with a reverse term in the context.
+	reverse bool
+	// list is set if the field is mapped to an ordered list, either with the
+	// "list" tag option or with a term in the context which has a @list
+	// container.
+	list bool
+	// container is the map container used for map fields: @language, @index,
+	// @id or @type. It's set either with the "language", "index", "id" or
+	// "type" tag option or with a term in the context which has a map
+	// container. It defaults to @index.
+	container string
+	// term is the term definition of the context the field refers to, if
+	// any.
+	term *Resource
+}
+
+func getField(ctx *Context, ft reflect.StructField) (f field, ok bool) {
 	k := ft.Name
 	if tag := ft.Tag.Get("jsonld"); tag != "" {
-		k = tag
-		if k == "-" {
-			return "", false
+		if tag == "-" {
+			return f, false
+		}
+		var opts []string
+		if i := strings.IndexByte(tag, ','); i >= 0 {
+			tag, opts = tag[:i], strings.Split(tag[i+1:], ",")
+		}
+		if tag != "" {
+			k = tag
+		}
+		for _, opt := range opts {
+			switch opt {
+			case "reverse":
+				f.reverse = true
+			case "list":
+				f.list = true
+			case "language", "index", "id", "type":
+				f.container = "@" + opt
+			}
 		}
 	}
 	if ctx != nil {
+		if term, ok := ctx.Terms[k]; ok {
+			f.term = term
+			f.reverse = f.reverse || termIsReverse(term)
+			f.list = f.list || termHasContainer(term, "@list")
+			if f.container == "" {
+				f.container = termMapContainer(term)
+			}
+		}
 		k = ctx.expand(k)
 	}
-	return k, true
+	if f.container == "" {
+		f.container = "@index"
+	}
+	f.uri = k
+	return f, true
+}
+
+// valueContext returns the context used for the values of a field: the
+// scoped context of the field term is applied to ctx, and contexts which
+// aren't propagated are reverted.
+func (p *processor) valueContext(ctx *Context, f field) (*Context, error) {
+	if ctx != nil && ctx.previous != nil {
+		ctx = ctx.previous
+	}
+	if _, ok := termContext(f.term); !ok {
+		return ctx, nil
+	}
+	return p.applyScopedContext(ctx, f.term, propertyScope)
+}
+
+// isMultiValued checks whether values of type t are stored as multiple
+// property values.
+func isMultiValued(t reflect.Type) bool {
+	if t == reflect.TypeOf(List(nil)) {
+		return false
+	}
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		return t.Elem().Kind() != reflect.Uint8
+	default:
+		return false
+	}
 }
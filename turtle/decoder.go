@@ -0,0 +1,828 @@
+package turtle
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/emersion/go-jsonld"
+)
+
+// Decoder reads Turtle and TriG documents.
+type Decoder struct {
+	// Context contains the prefixes and base IRI used to parse the document.
+	// Declarations found in the document are added to it. If nil, a new
+	// context is created.
+	Context *jsonld.Context
+	// TriG, if set, enables the TriG syntax.
+	TriG bool
+
+	r io.Reader
+}
+
+// NewDecoder creates a new Turtle decoder.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: r}
+}
+
+// Decode reads a whole document.
+func (d *Decoder) Decode() ([]jsonld.Quad, error) {
+	b, err := ioutil.ReadAll(d.r)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.Context == nil {
+		d.Context = new(jsonld.Context)
+	}
+	if d.Context.Terms == nil {
+		d.Context.Terms = make(map[string]*jsonld.Resource)
+	}
+
+	p := &parser{
+		s: []rune(string(b)),
+		ctx: d.Context,
+		trig: d.TriG,
+		bnodes: make(map[string]jsonld.BlankNode),
+	}
+	if err := p.parseDocument(); err != nil {
+		return nil, err
+	}
+	return p.quads, nil
+}
+
+type parser struct {
+	s []rune
+	pos int
+	ctx *jsonld.Context
+	trig bool
+
+	graph jsonld.RDFTerm
+	quads []jsonld.Quad
+	bnodes map[string]jsonld.BlankNode
+	counter int
+}
+
+func (p *parser) errorf(format string, v ...interface{}) error {
+	line, col := 1, 1
+	for _, c := range p.s[:p.pos] {
+		if c == '\n' {
+			line++
+			col = 1
+		} else {
+			col++
+		}
+	}
+	return &SyntaxError{Line: line, Column: col, Msg: fmt.Sprintf(format, v...)}
+}
+
+func (p *parser) eof() bool {
+	return p.pos >= len(p.s)
+}
+
+func (p *parser) peek() rune {
+	return p.peekAt(0)
+}
+
+func (p *parser) peekAt(i int) rune {
+	if p.pos+i >= len(p.s) {
+		return 0
+	}
+	return p.s[p.pos+i]
+}
+
+func (p *parser) hasPrefix(s string) bool {
+	return strings.HasPrefix(string(p.s[p.pos:min(p.pos+len(s), len(p.s))]), s)
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// skipSpace skips whitespace and comments.
+func (p *parser) skipSpace() {
+	for !p.eof() {
+		switch c := p.peek(); {
+		case c == '#':
+			for !p.eof() && p.peek() != '\n' {
+				p.pos++
+			}
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			p.pos++
+		default:
+			return
+		}
+	}
+}
+
+func (p *parser) expect(c rune) error {
+	p.skipSpace()
+	if p.peek() != c {
+		return p.errorf("expected %q", c)
+	}
+	p.pos++
+	return nil
+}
+
+// keyword checks whether the input starts with a case-insensitive keyword
+// followed by a delimiter, and consumes it.
+func (p *parser) keyword(kw string) bool {
+	n := len(kw)
+	if p.pos+n > len(p.s) || !strings.EqualFold(string(p.s[p.pos:p.pos+n]), kw) {
+		return false
+	}
+	if c := p.peekAt(n); isPNChar(c, false) || c == ':' {
+		return false
+	}
+	p.pos += n
+	return true
+}
+
+func (p *parser) emit(s, pred, o jsonld.RDFTerm) {
+	p.quads = append(p.quads, jsonld.Quad{Subject: s, Predicate: pred, Object: o, Graph: p.graph})
+}
+
+func (p *parser) newBlankNode() jsonld.BlankNode {
+	bn := jsonld.BlankNode("b" + strconv.Itoa(p.counter))
+	p.counter++
+	return bn
+}
+
+func (p *parser) parseDocument() error {
+	for {
+		p.skipSpace()
+		if p.eof() {
+			return nil
+		}
+
+		switch {
+		case p.hasPrefix("@prefix"):
+			p.pos += len("@prefix")
+			if err := p.parsePrefix(true); err != nil {
+				return err
+			}
+		case p.hasPrefix("@base"):
+			p.pos += len("@base")
+			if err := p.parseBase(true); err != nil {
+				return err
+			}
+		case p.keyword("PREFIX"):
+			if err := p.parsePrefix(false); err != nil {
+				return err
+			}
+		case p.keyword("BASE"):
+			if err := p.parseBase(false); err != nil {
+				return err
+			}
+		case p.trig && p.keyword("GRAPH"):
+			p.skipSpace()
+			label, err := p.parseGraphLabel()
+			if err != nil {
+				return err
+			}
+			if err := p.parseWrappedGraph(label); err != nil {
+				return err
+			}
+		case p.trig && p.peek() == '{':
+			if err := p.parseWrappedGraph(nil); err != nil {
+				return err
+			}
+		default:
+			if err := p.parseBlock(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (p *parser) parsePrefix(turtle bool) error {
+	p.skipSpace()
+	start := p.pos
+	for !p.eof() && (isPNChar(p.peek(), p.pos == start) || (p.pos > start && p.peek() == '.')) {
+		p.pos++
+	}
+	prefix := string(p.s[start:p.pos])
+	if p.peek() != ':' || strings.HasSuffix(prefix, ".") {
+		return p.errorf("invalid prefix name")
+	}
+	p.pos++
+
+	p.skipSpace()
+	iri, err := p.parseIRIRef()
+	if err != nil {
+		return err
+	}
+	if turtle {
+		if err := p.expect('.'); err != nil {
+			return err
+		}
+	}
+
+	if prefix == "" {
+		p.ctx.Vocab = string(iri)
+		return nil
+	}
+	term := &jsonld.Resource{ID: string(iri)}
+	if iri != "" && !strings.ContainsAny(string(iri[len(iri)-1:]), ":/?#[]@") {
+		term.Props = jsonld.Props{"@prefix": {true}}
+	}
+	p.ctx.Terms[prefix] = term
+	return nil
+}
+
+func (p *parser) parseBase(turtle bool) error {
+	p.skipSpace()
+	iri, err := p.parseIRIRef()
+	if err != nil {
+		return err
+	}
+	if turtle {
+		if err := p.expect('.'); err != nil {
+			return err
+		}
+	}
+	p.ctx.Base = string(iri)
+	return nil
+}
+
+// parseBlock parses a triples statement, or in TriG a labelled graph.
+func (p *parser) parseBlock() error {
+	switch p.peek() {
+	case '[':
+		if p.trig && p.isAnon() {
+			label, err := p.parseGraphLabel()
+			if err != nil {
+				return err
+			}
+			p.skipSpace()
+			if p.peek() == '{' {
+				return p.parseWrappedGraph(label)
+			}
+			return p.parseTriplesRest(label)
+		}
+	case '(':
+	default:
+		if p.trig {
+			label, err := p.parseGraphLabel()
+			if err != nil {
+				return err
+			}
+			p.skipSpace()
+			if p.peek() == '{' {
+				return p.parseWrappedGraph(label)
+			}
+			return p.parseTriplesRest(label)
+		}
+	}
+
+	if err := p.parseTriples(); err != nil {
+		return err
+	}
+	return p.expect('.')
+}
+
+// isAnon checks whether the input starts with an anonymous blank node "[]".
+func (p *parser) isAnon() bool {
+	i := 1
+	for {
+		switch p.peekAt(i) {
+		case ' ', '\t', '\n', '\r':
+			i++
+		case ']':
+			return true
+		default:
+			return false
+		}
+	}
+}
+
+func (p *parser) parseGraphLabel() (jsonld.RDFTerm, error) {
+	if p.peek() == '[' {
+		if !p.isAnon() {
+			return nil, p.errorf("expected graph label")
+		}
+		if err := p.expect('['); err != nil {
+			return nil, err
+		}
+		if err := p.expect(']'); err != nil {
+			return nil, err
+		}
+		return p.newBlankNode(), nil
+	}
+	return p.parseIRIOrBlankNode()
+}
+
+func (p *parser) parseWrappedGraph(label jsonld.RDFTerm) error {
+	if err := p.expect('{'); err != nil {
+		return err
+	}
+
+	p.graph = label
+	defer func() {
+		p.graph = nil
+	}()
+
+	for {
+		p.skipSpace()
+		if p.peek() == '}' {
+			p.pos++
+			return nil
+		}
+		if err := p.parseTriples(); err != nil {
+			return err
+		}
+		p.skipSpace()
+		if p.peek() == '.' {
+			p.pos++
+		} else if p.peek() != '}' {
+			return p.errorf("expected '.' or '}'")
+		}
+	}
+}
+
+// parseTriples parses a subject followed by a predicate-object list.
+func (p *parser) parseTriples() error {
+	p.skipSpace()
+	switch p.peek() {
+	case '[':
+		if p.isAnon() {
+			break
+		}
+		subject, err := p.parseBlankNodePropertyList()
+		if err != nil {
+			return err
+		}
+		p.skipSpace()
+		if p.peek() == '.' || p.peek() == '}' || p.eof() {
+			return nil
+		}
+		return p.parsePredicateObjectList(subject)
+	case '(':
+		subject, err := p.parseCollection()
+		if err != nil {
+			return err
+		}
+		return p.parsePredicateObjectList(subject)
+	}
+
+	var subject jsonld.RDFTerm
+	var err error
+	if p.peek() == '[' {
+		subject, err = p.parseGraphLabel()
+	} else {
+		subject, err = p.parseIRIOrBlankNode()
+	}
+	if err != nil {
+		return err
+	}
+	return p.parsePredicateObjectList(subject)
+}
+
+// parseTriplesRest parses the predicate-object list following a subject and
+// the final '.'.
+func (p *parser) parseTriplesRest(subject jsonld.RDFTerm) error {
+	if err := p.parsePredicateObjectList(subject); err != nil {
+		return err
+	}
+	return p.expect('.')
+}
+
+func (p *parser) parsePredicateObjectList(subject jsonld.RDFTerm) error {
+	for {
+		p.skipSpace()
+		predicate, err := p.parseVerb()
+		if err != nil {
+			return err
+		}
+
+		for {
+			p.skipSpace()
+			object, err := p.parseObject()
+			if err != nil {
+				return err
+			}
+			p.emit(subject, predicate, object)
+
+			p.skipSpace()
+			if p.peek() != ',' {
+				break
+			}
+			p.pos++
+		}
+
+		if p.peek() != ';' {
+			return nil
+		}
+		for p.peek() == ';' {
+			p.pos++
+			p.skipSpace()
+		}
+		if c := p.peek(); c == '.' || c == ']' || c == '}' || p.eof() {
+			return nil
+		}
+	}
+}
+
+func (p *parser) parseVerb() (jsonld.IRI, error) {
+	if p.peek() == 'a' {
+		if c := p.peekAt(1); !isPNChar(c, false) && c != ':' && c != '.' {
+			p.pos++
+			return rdfType, nil
+		}
+	}
+	return p.parseIRI()
+}
+
+func (p *parser) parseObject() (jsonld.RDFTerm, error) {
+	switch c := p.peek(); {
+	case c == '[':
+		if p.isAnon() {
+			return p.parseGraphLabel()
+		}
+		return p.parseBlankNodePropertyList()
+	case c == '(':
+		return p.parseCollection()
+	case c == '"' || c == '\'':
+		return p.parseRDFLiteral()
+	case c == '+' || c == '-' || c == '.' || (c >= '0' && c <= '9'):
+		return p.parseNumericLiteral()
+	case p.keyword("true"):
+		return jsonld.Literal{Value: "true", Datatype: xsdBoolean}, nil
+	case p.keyword("false"):
+		return jsonld.Literal{Value: "false", Datatype: xsdBoolean}, nil
+	default:
+		return p.parseIRIOrBlankNode()
+	}
+}
+
+func (p *parser) parseBlankNodePropertyList() (jsonld.RDFTerm, error) {
+	p.pos++ // '['
+	bn := p.newBlankNode()
+	if err := p.parsePredicateObjectList(bn); err != nil {
+		return nil, err
+	}
+	if err := p.expect(']'); err != nil {
+		return nil, err
+	}
+	return bn, nil
+}
+
+func (p *parser) parseCollection() (jsonld.RDFTerm, error) {
+	p.pos++ // '('
+
+	var head, prev jsonld.RDFTerm = jsonld.IRI(rdfNil), nil
+	for {
+		p.skipSpace()
+		if p.eof() {
+			return nil, p.errorf("unterminated collection")
+		} else if p.peek() == ')' {
+			p.pos++
+			break
+		}
+
+		node := p.newBlankNode()
+		if prev == nil {
+			head = node
+		} else {
+			p.emit(prev, jsonld.IRI(rdfRest), node)
+		}
+		object, err := p.parseObject()
+		if err != nil {
+			return nil, err
+		}
+		p.emit(node, jsonld.IRI(rdfFirst), object)
+		prev = node
+	}
+	if prev != nil {
+		p.emit(prev, jsonld.IRI(rdfRest), jsonld.IRI(rdfNil))
+	}
+	return head, nil
+}
+
+func (p *parser) parseIRIOrBlankNode() (jsonld.RDFTerm, error) {
+	if p.peek() == '_' && p.peekAt(1) == ':' {
+		return p.parseBlankNode()
+	}
+	return p.parseIRI()
+}
+
+func (p *parser) parseBlankNode() (jsonld.RDFTerm, error) {
+	p.pos += 2 // "_:"
+	start := p.pos
+	for !p.eof() && (isPNChar(p.peek(), p.pos == start) || (p.pos == start && p.peek() >= '0' && p.peek() <= '9') || (p.pos > start && p.peek() == '.')) {
+		p.pos++
+	}
+	for p.pos > start && p.s[p.pos-1] == '.' {
+		p.pos--
+	}
+	if p.pos == start {
+		return nil, p.errorf("empty blank node label")
+	}
+
+	label := string(p.s[start:p.pos])
+	bn, ok := p.bnodes[label]
+	if !ok {
+		bn = p.newBlankNode()
+		p.bnodes[label] = bn
+	}
+	return bn, nil
+}
+
+// parseIRI parses an IRI reference or a prefixed name.
+func (p *parser) parseIRI() (jsonld.IRI, error) {
+	if p.peek() == '<' {
+		return p.parseIRIRef()
+	}
+	return p.parsePrefixedName()
+}
+
+func (p *parser) parseIRIRef() (jsonld.IRI, error) {
+	if p.peek() != '<' {
+		return "", p.errorf("expected IRI")
+	}
+	p.pos++
+
+	var sb strings.Builder
+	for {
+		if p.eof() {
+			return "", p.errorf("unterminated IRI")
+		}
+		c := p.peek()
+		switch {
+		case c == '>':
+			p.pos++
+			return jsonld.IRI(p.resolve(sb.String())), nil
+		case c == '\\':
+			r, err := p.parseUCHAR()
+			if err != nil {
+				return "", err
+			}
+			sb.WriteRune(r)
+		case c <= 0x20 || strings.ContainsRune("<\"{}|^`", c):
+			return "", p.errorf("invalid character %q in IRI", c)
+		default:
+			sb.WriteRune(c)
+			p.pos++
+		}
+	}
+}
+
+// resolve resolves an IRI reference against the base IRI.
+func (p *parser) resolve(ref string) string {
+	return jsonld.ResolveIRI(p.ctx.Base, ref)
+}
+
+func (p *parser) parsePrefixedName() (jsonld.IRI, error) {
+	start := p.pos
+	for !p.eof() && (isPNChar(p.peek(), p.pos == start) || (p.pos > start && p.peek() == '.')) {
+		p.pos++
+	}
+	if p.peek() != ':' || (p.pos > start && p.s[p.pos-1] == '.') {
+		p.pos = start
+		return "", p.errorf("expected IRI")
+	}
+	prefix := string(p.s[start:p.pos])
+	p.pos++
+
+	var ns string
+	if prefix == "" {
+		if p.ctx.Vocab == "" {
+			p.pos = start
+			return "", p.errorf("undefined empty prefix")
+		}
+		ns = p.ctx.Vocab
+	} else if term := p.ctx.Terms[prefix]; term != nil && term.ID != "" {
+		ns = term.ID
+	} else {
+		p.pos = start
+		return "", p.errorf("undefined prefix %q", prefix)
+	}
+
+	var sb strings.Builder
+	first := true
+loop:
+	for !p.eof() {
+		c := p.peek()
+		switch {
+		case c == '%':
+			if !isHex(p.peekAt(1)) || !isHex(p.peekAt(2)) {
+				return "", p.errorf("invalid percent-encoding")
+			}
+			sb.WriteString(string(p.s[p.pos : p.pos+3]))
+			p.pos += 3
+		case c == '\\':
+			if !strings.ContainsRune("_~.-!$&'()*+,;=/?#@%", p.peekAt(1)) {
+				return "", p.errorf("invalid escape sequence")
+			}
+			sb.WriteRune(p.peekAt(1))
+			p.pos += 2
+		case c == ':' || isPNChar(c, first) || (c >= '0' && c <= '9') || (!first && c == '.'):
+			sb.WriteRune(c)
+			p.pos++
+		default:
+			break loop
+		}
+		first = false
+	}
+
+	local := sb.String()
+	for strings.HasSuffix(local, ".") && p.s[p.pos-1] == '.' && p.s[p.pos-2] != '\\' {
+		local = local[:len(local)-1]
+		p.pos--
+	}
+	return jsonld.IRI(ns + local), nil
+}
+
+func isHex(c rune) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+// isPNChar checks whether c is allowed in a prefix name, a local name or a
+// blank node label. Digits and '-' are not allowed in first position.
+func isPNChar(c rune, first bool) bool {
+	switch {
+	case c == '_' || unicode.IsLetter(c):
+		return true
+	case first:
+		return false
+	default:
+		return c == '-' || (c >= '0' && c <= '9') || c == 0xB7 || unicode.Is(unicode.Mn, c) || unicode.Is(unicode.Pc, c)
+	}
+}
+
+func (p *parser) parseRDFLiteral() (jsonld.RDFTerm, error) {
+	value, err := p.parseString()
+	if err != nil {
+		return nil, err
+	}
+
+	lit := jsonld.Literal{Value: value, Datatype: xsdString}
+	switch {
+	case p.peek() == '@':
+		p.pos++
+		start := p.pos
+		for !p.eof() {
+			c := p.peek()
+			isAlpha := (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+			if !isAlpha && (p.pos == start || !(c >= '0' && c <= '9' || c == '-')) {
+				break
+			}
+			p.pos++
+		}
+		lang := string(p.s[start:p.pos])
+		if lang == "" || strings.HasSuffix(lang, "-") || strings.Contains(lang, "--") {
+			p.pos = start
+			return nil, p.errorf("invalid language tag")
+		}
+		lit.Language = lang
+		lit.Datatype = rdfLangString
+	case p.hasPrefix("^^"):
+		p.pos += 2
+		if lit.Datatype, err = p.parseIRI(); err != nil {
+			return nil, err
+		}
+	}
+	return lit, nil
+}
+
+func (p *parser) parseString() (string, error) {
+	q := p.peek()
+	long := p.hasPrefix(strings.Repeat(string(q), 3))
+	if long {
+		p.pos += 3
+	} else {
+		p.pos++
+	}
+
+	var sb strings.Builder
+	for {
+		if p.eof() {
+			return "", p.errorf("unterminated string")
+		}
+		c := p.peek()
+		switch {
+		case long && p.hasPrefix(strings.Repeat(string(q), 3)):
+			p.pos += 3
+			// A long string may end with up to two quotes
+			for p.peek() == q {
+				sb.WriteRune(q)
+				p.pos++
+			}
+			return sb.String(), nil
+		case !long && c == q:
+			p.pos++
+			return sb.String(), nil
+		case !long && (c == '\n' || c == '\r'):
+			return "", p.errorf("unterminated string")
+		case c == '\\':
+			r, err := p.parseEscape()
+			if err != nil {
+				return "", err
+			}
+			sb.WriteRune(r)
+		default:
+			sb.WriteRune(c)
+			p.pos++
+		}
+	}
+}
+
+func (p *parser) parseEscape() (rune, error) {
+	var r rune
+	switch p.peekAt(1) {
+	case 't':
+		r = '\t'
+	case 'b':
+		r = '\b'
+	case 'n':
+		r = '\n'
+	case 'r':
+		r = '\r'
+	case 'f':
+		r = '\f'
+	case '"', '\'', '\\':
+		r = p.peekAt(1)
+	case 'u', 'U':
+		return p.parseUCHAR()
+	default:
+		return 0, p.errorf("invalid escape sequence")
+	}
+	p.pos += 2
+	return r, nil
+}
+
+// parseUCHAR parses a \uXXXX or \UXXXXXXXX escape sequence.
+func (p *parser) parseUCHAR() (rune, error) {
+	var n int
+	switch p.peekAt(1) {
+	case 'u':
+		n = 4
+	case 'U':
+		n = 8
+	default:
+		return 0, p.errorf("invalid escape sequence")
+	}
+	if p.pos+2+n > len(p.s) {
+		return 0, p.errorf("unterminated escape sequence")
+	}
+	r, err := strconv.ParseUint(string(p.s[p.pos+2:p.pos+2+n]), 16, 32)
+	if err != nil {
+		return 0, p.errorf("invalid escape sequence")
+	}
+	p.pos += 2 + n
+	return rune(r), nil
+}
+
+func (p *parser) parseNumericLiteral() (jsonld.RDFTerm, error) {
+	start := p.pos
+	isDigit := func(c rune) bool { return c >= '0' && c <= '9' }
+	digits := func() int {
+		n := 0
+		for isDigit(p.peek()) {
+			p.pos++
+			n++
+		}
+		return n
+	}
+	exponent := func() bool {
+		if c := p.peek(); c != 'e' && c != 'E' {
+			return false
+		}
+		i := 1
+		if c := p.peekAt(1); c == '+' || c == '-' {
+			i++
+		}
+		if !isDigit(p.peekAt(i)) {
+			return false
+		}
+		p.pos += i
+		digits()
+		return true
+	}
+
+	if c := p.peek(); c == '+' || c == '-' {
+		p.pos++
+	}
+	n := digits()
+	datatype := xsdInteger
+	if p.peek() == '.' && (isDigit(p.peekAt(1)) || (n > 0 && (p.peekAt(1) == 'e' || p.peekAt(1) == 'E'))) {
+		p.pos++
+		n += digits()
+		datatype = xsdDecimal
+	}
+	if n == 0 {
+		p.pos = start
+		return nil, p.errorf("invalid number")
+	}
+	if exponent() {
+		datatype = xsdDouble
+	}
+
+	return jsonld.Literal{Value: string(p.s[start:p.pos]), Datatype: jsonld.IRI(datatype)}, nil
+}
@@ -0,0 +1,334 @@
+package turtle
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/emersion/go-jsonld"
+)
+
+// Encoder writes Turtle and TriG documents.
+type Encoder struct {
+	// Context, if non-nil, contains the prefixes and base IRI used to
+	// abbreviate IRIs.
+	Context *jsonld.Context
+	// TriG, if set, enables the TriG syntax. Otherwise, statements in named
+	// graphs cannot be encoded.
+	TriG bool
+
+	w io.Writer
+}
+
+// NewEncoder creates a new Turtle encoder.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+type prefix struct {
+	name, ns string
+}
+
+type graphStatements struct {
+	name jsonld.RDFTerm
+	subjects []*subjectStatements
+}
+
+type subjectStatements struct {
+	subject jsonld.RDFTerm
+	predicates []*predicateObjects
+}
+
+type predicateObjects struct {
+	predicate jsonld.RDFTerm
+	objects []jsonld.RDFTerm
+}
+
+// Encode writes a whole document.
+func (e *Encoder) Encode(quads []jsonld.Quad) error {
+	graphs, err := e.group(quads)
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(e.w)
+	ew := &encodeWriter{w: bw, prefixes: e.prefixes()}
+	if e.Context != nil && e.Context.Base != "" {
+		ew.base = e.Context.Base
+		ew.printf("@base <%v> .\n", escapeIRI(ew.base))
+	}
+	sorted := append([]prefix(nil), ew.prefixes...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].name < sorted[j].name
+	})
+	for _, p := range sorted {
+		ew.printf("@prefix %v: <%v> .\n", p.name, escapeIRI(p.ns))
+	}
+
+	for i, g := range graphs {
+		if i > 0 || ew.base != "" || len(sorted) > 0 {
+			ew.printf("\n")
+		}
+
+		indent := ""
+		if g.name != nil {
+			ew.printf("%v {\n", ew.formatTerm(g.name))
+			indent = "\t"
+		}
+		for j, s := range g.subjects {
+			if j > 0 {
+				ew.printf("\n")
+			}
+			ew.printf("%v%v", indent, ew.formatTerm(s.subject))
+			for k, p := range s.predicates {
+				if k > 0 {
+					ew.printf(" ;\n%v\t", indent)
+				} else {
+					ew.printf(" ")
+				}
+				if p.predicate == jsonld.IRI(rdfType) {
+					ew.printf("a")
+				} else {
+					ew.printf("%v", ew.formatTerm(p.predicate))
+				}
+				for l, o := range p.objects {
+					if l > 0 {
+						ew.printf(",")
+					}
+					ew.printf(" %v", ew.formatTerm(o))
+				}
+			}
+			ew.printf(" .\n")
+		}
+		if g.name != nil {
+			ew.printf("}\n")
+		}
+	}
+
+	if ew.err != nil {
+		return ew.err
+	}
+	return bw.Flush()
+}
+
+// group groups statements by graph, subject and predicate, in order of
+// appearance. The default graph comes first, and rdf:type comes first for
+// each subject.
+func (e *Encoder) group(quads []jsonld.Quad) ([]*graphStatements, error) {
+	graphs := []*graphStatements{{}}
+	graphIndex := map[jsonld.RDFTerm]*graphStatements{nil: graphs[0]}
+	subjectIndex := make(map[[2]jsonld.RDFTerm]*subjectStatements)
+	predicateIndex := make(map[[3]jsonld.RDFTerm]*predicateObjects)
+
+	for _, q := range quads {
+		if q.Subject == nil || q.Predicate == nil || q.Object == nil {
+			return nil, errors.New("turtle: incomplete statement")
+		}
+		if _, ok := q.Subject.(jsonld.Literal); ok {
+			return nil, errors.New("turtle: literal used as subject")
+		}
+		if _, ok := q.Predicate.(jsonld.IRI); !ok {
+			return nil, errors.New("turtle: predicate must be an IRI")
+		}
+		if _, ok := q.Graph.(jsonld.Literal); ok {
+			return nil, errors.New("turtle: literal used as graph name")
+		}
+		if q.Graph != nil && !e.TriG {
+			return nil, errors.New("turtle: named graphs require TriG")
+		}
+
+		g, ok := graphIndex[q.Graph]
+		if !ok {
+			g = &graphStatements{name: q.Graph}
+			graphIndex[q.Graph] = g
+			graphs = append(graphs, g)
+		}
+
+		sk := [2]jsonld.RDFTerm{q.Graph, q.Subject}
+		s, ok := subjectIndex[sk]
+		if !ok {
+			s = &subjectStatements{subject: q.Subject}
+			subjectIndex[sk] = s
+			g.subjects = append(g.subjects, s)
+		}
+
+		pk := [3]jsonld.RDFTerm{q.Graph, q.Subject, q.Predicate}
+		p, ok := predicateIndex[pk]
+		if !ok {
+			p = &predicateObjects{predicate: q.Predicate}
+			predicateIndex[pk] = p
+			if q.Predicate == jsonld.IRI(rdfType) {
+				s.predicates = append([]*predicateObjects{p}, s.predicates...)
+			} else {
+				s.predicates = append(s.predicates, p)
+			}
+		}
+		p.objects = append(p.objects, q.Object)
+	}
+
+	if len(graphs[0].subjects) == 0 {
+		graphs = graphs[1:]
+	}
+	return graphs, nil
+}
+
+// prefixes returns the prefixes defined in the context, longest namespace
+// first.
+func (e *Encoder) prefixes() []prefix {
+	if e.Context == nil {
+		return nil
+	}
+
+	var l []prefix
+	if e.Context.Vocab != "" {
+		l = append(l, prefix{"", e.Context.Vocab})
+	}
+	for name, term := range e.Context.Terms {
+		if term == nil || term.ID == "" || !isPrefixName(name) {
+			continue
+		}
+		isPrefix, ok := term.Props.Get("@prefix").(bool)
+		if !ok {
+			isPrefix = strings.ContainsAny(term.ID[len(term.ID)-1:], ":/?#[]@")
+		}
+		if isPrefix {
+			l = append(l, prefix{name, term.ID})
+		}
+	}
+
+	sort.Slice(l, func(i, j int) bool {
+		if len(l[i].ns) != len(l[j].ns) {
+			return len(l[i].ns) > len(l[j].ns)
+		}
+		return l[i].name < l[j].name
+	})
+	return l
+}
+
+func isPrefixName(s string) bool {
+	if s == "" || strings.HasSuffix(s, ".") {
+		return false
+	}
+	for i, c := range s {
+		if !isPNChar(c, i == 0) && (i == 0 || c != '.') {
+			return false
+		}
+	}
+	return true
+}
+
+func isLocalName(s string) bool {
+	if strings.HasSuffix(s, ".") {
+		return false
+	}
+	for i, c := range s {
+		if !isPNChar(c, i == 0) && !(c >= '0' && c <= '9') && c != ':' && (i == 0 || c != '.') {
+			return false
+		}
+	}
+	return true
+}
+
+type encodeWriter struct {
+	w *bufio.Writer
+	err error
+	prefixes []prefix
+	base string
+}
+
+func (ew *encodeWriter) printf(format string, v ...interface{}) {
+	if ew.err != nil {
+		return
+	}
+	_, ew.err = fmt.Fprintf(ew.w, format, v...)
+}
+
+func (ew *encodeWriter) formatTerm(t jsonld.RDFTerm) string {
+	switch t := t.(type) {
+	case jsonld.IRI:
+		return ew.formatIRI(string(t))
+	case jsonld.Literal:
+		return ew.formatLiteral(t)
+	default:
+		return t.String()
+	}
+}
+
+func (ew *encodeWriter) formatIRI(iri string) string {
+	for _, p := range ew.prefixes {
+		if strings.HasPrefix(iri, p.ns) && isLocalName(iri[len(p.ns):]) {
+			return p.name + ":" + iri[len(p.ns):]
+		}
+	}
+	if ew.base != "" && strings.HasPrefix(iri, ew.base) {
+		rel := iri[len(ew.base):]
+		first := rel
+		if i := strings.IndexAny(rel, "/?#"); i >= 0 {
+			first = rel[:i]
+		}
+		if !strings.Contains(first, ":") && !strings.Contains(ew.base, "#") {
+			return "<" + escapeIRI(rel) + ">"
+		}
+	}
+	return "<" + escapeIRI(iri) + ">"
+}
+
+var (
+	integerRegexp = regexp.MustCompile(`^[+-]?[0-9]+$`)
+	decimalRegexp = regexp.MustCompile(`^[+-]?[0-9]*\.[0-9]+$`)
+	doubleRegexp = regexp.MustCompile(`^[+-]?([0-9]+\.[0-9]*|\.[0-9]+|[0-9]+)[eE][+-]?[0-9]+$`)
+)
+
+func (ew *encodeWriter) formatLiteral(lit jsonld.Literal) string {
+	switch lit.Datatype {
+	case xsdInteger:
+		if integerRegexp.MatchString(lit.Value) {
+			return lit.Value
+		}
+	case xsdDecimal:
+		if decimalRegexp.MatchString(lit.Value) {
+			return lit.Value
+		}
+	case xsdDouble:
+		if doubleRegexp.MatchString(lit.Value) {
+			return lit.Value
+		}
+	case xsdBoolean:
+		if lit.Value == "true" || lit.Value == "false" {
+			return lit.Value
+		}
+	}
+
+	s := `"` + stringReplacer.Replace(lit.Value) + `"`
+	if lit.Language != "" {
+		return s + "@" + lit.Language
+	}
+	if lit.Datatype != "" && lit.Datatype != xsdString {
+		return s + "^^" + ew.formatIRI(string(lit.Datatype))
+	}
+	return s
+}
+
+var stringReplacer = strings.NewReplacer(
+	`\`, `\\`,
+	`"`, `\"`,
+	"\n", `\n`,
+	"\r", `\r`,
+)
+
+// escapeIRI escapes characters which are not allowed in IRI references.
+func escapeIRI(iri string) string {
+	var sb strings.Builder
+	for _, c := range iri {
+		if c <= 0x20 || strings.ContainsRune("<>\"{}|^`\\", c) {
+			sb.WriteString(fmt.Sprintf(`\u%04X`, c))
+		} else {
+			sb.WriteRune(c)
+		}
+	}
+	return sb.String()
+}
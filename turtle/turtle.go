@@ -0,0 +1,71 @@
+// Package turtle implements the Turtle and TriG RDF formats, as defined in
+// https://www.w3.org/TR/turtle/ and https://www.w3.org/TR/trig/.
+//
+// Prefix declarations are represented as JSON-LD context terms, the empty
+// prefix as the context vocabulary and the base IRI as the context base.
+package turtle
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/emersion/go-jsonld"
+)
+
+const (
+	nsRDF = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	nsXSD = "http://www.w3.org/2001/XMLSchema#"
+)
+
+const (
+	rdfType = nsRDF + "type"
+	rdfFirst = nsRDF + "first"
+	rdfRest = nsRDF + "rest"
+	rdfNil = nsRDF + "nil"
+	rdfLangString = nsRDF + "langString"
+)
+
+const (
+	xsdString = nsXSD + "string"
+	xsdBoolean = nsXSD + "boolean"
+	xsdInteger = nsXSD + "integer"
+	xsdDecimal = nsXSD + "decimal"
+	xsdDouble = nsXSD + "double"
+)
+
+// SyntaxError is returned when the input is not valid Turtle or TriG.
+type SyntaxError struct {
+	Line, Column int
+	Msg string
+}
+
+func (err *SyntaxError) Error() string {
+	return fmt.Sprintf("turtle: line %v, column %v: %v", err.Line, err.Column, err.Msg)
+}
+
+// Unmarshal parses a TriG document, which may also be a Turtle document. The
+// returned context contains the prefixes and base IRI declared in the
+// document.
+func Unmarshal(b []byte) ([]jsonld.Quad, *jsonld.Context, error) {
+	dec := NewDecoder(bytes.NewReader(b))
+	dec.TriG = true
+	quads, err := dec.Decode()
+	if err != nil {
+		return nil, nil, err
+	}
+	return quads, dec.Context, nil
+}
+
+// Marshal returns the TriG encoding of quads, using the prefixes and base IRI
+// of ctx. If all quads belong to the default graph, the result is also a valid
+// Turtle document.
+func Marshal(quads []jsonld.Quad, ctx *jsonld.Context) ([]byte, error) {
+	var b bytes.Buffer
+	enc := NewEncoder(&b)
+	enc.Context = ctx
+	enc.TriG = true
+	if err := enc.Encode(quads); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
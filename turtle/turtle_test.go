@@ -0,0 +1,149 @@
+package turtle
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/emersion/go-jsonld"
+)
+
+const testTriG = `@base <http://example.org/> .
+@prefix ex: <http://example.org/vocab#> .
+PREFIX : <http://example.org/default#>
+
+<alice> a ex:Person ;
+	ex:name "Alice", "Alicia"@es ;
+	ex:age 42 ;
+	ex:knows [ ex:name "Bob" ], _:carol ;
+	:tags ( "a" 1.5 true ) .
+
+_:carol ex:name """Carol
+"the third\"""" .
+
+GRAPH <g> {
+	<alice> ex:score 1e3 ; ex:date "2020-01-01"^^<http://www.w3.org/2001/XMLSchema#date> .
+}
+`
+
+func lit(value, datatype string) jsonld.Literal {
+	return jsonld.Literal{Value: value, Datatype: jsonld.IRI(datatype)}
+}
+
+var (
+	alice = jsonld.IRI("http://example.org/alice")
+	exName = jsonld.IRI("http://example.org/vocab#name")
+)
+
+var testTriGQuads = []jsonld.Quad{
+	{Subject: alice, Predicate: jsonld.IRI(rdfType), Object: jsonld.IRI("http://example.org/vocab#Person")},
+	{Subject: alice, Predicate: exName, Object: lit("Alice", xsdString)},
+	{Subject: alice, Predicate: exName, Object: jsonld.Literal{Value: "Alicia", Datatype: rdfLangString, Language: "es"}},
+	{Subject: alice, Predicate: jsonld.IRI("http://example.org/vocab#age"), Object: lit("42", xsdInteger)},
+	{Subject: jsonld.BlankNode("b0"), Predicate: exName, Object: lit("Bob", xsdString)},
+	{Subject: alice, Predicate: jsonld.IRI("http://example.org/vocab#knows"), Object: jsonld.BlankNode("b0")},
+	{Subject: alice, Predicate: jsonld.IRI("http://example.org/vocab#knows"), Object: jsonld.BlankNode("b1")},
+	{Subject: jsonld.BlankNode("b2"), Predicate: jsonld.IRI(rdfFirst), Object: lit("a", xsdString)},
+	{Subject: jsonld.BlankNode("b2"), Predicate: jsonld.IRI(rdfRest), Object: jsonld.BlankNode("b3")},
+	{Subject: jsonld.BlankNode("b3"), Predicate: jsonld.IRI(rdfFirst), Object: lit("1.5", xsdDecimal)},
+	{Subject: jsonld.BlankNode("b3"), Predicate: jsonld.IRI(rdfRest), Object: jsonld.BlankNode("b4")},
+	{Subject: jsonld.BlankNode("b4"), Predicate: jsonld.IRI(rdfFirst), Object: lit("true", xsdBoolean)},
+	{Subject: jsonld.BlankNode("b4"), Predicate: jsonld.IRI(rdfRest), Object: jsonld.IRI(rdfNil)},
+	{Subject: alice, Predicate: jsonld.IRI("http://example.org/default#tags"), Object: jsonld.BlankNode("b2")},
+	{Subject: jsonld.BlankNode("b1"), Predicate: exName, Object: lit("Carol\n\"the third\"", xsdString)},
+	{Subject: alice, Predicate: jsonld.IRI("http://example.org/vocab#score"), Object: lit("1e3", xsdDouble), Graph: jsonld.IRI("http://example.org/g")},
+	{Subject: alice, Predicate: jsonld.IRI("http://example.org/vocab#date"), Object: lit("2020-01-01", "http://www.w3.org/2001/XMLSchema#date"), Graph: jsonld.IRI("http://example.org/g")},
+}
+
+func TestUnmarshal(t *testing.T) {
+	quads, ctx, err := Unmarshal([]byte(testTriG))
+	if err != nil {
+		t.Fatalf("Unmarshal() = %v", err)
+	}
+	if !reflect.DeepEqual(quads, testTriGQuads) {
+		t.Errorf("Unmarshal() = \n%#v\nwant \n%#v", quads, testTriGQuads)
+	}
+
+	if ctx.Base != "http://example.org/" {
+		t.Errorf("Unmarshal() base = %q", ctx.Base)
+	}
+	if ctx.Vocab != "http://example.org/default#" {
+		t.Errorf("Unmarshal() vocab = %q", ctx.Vocab)
+	}
+	if term := ctx.Terms["ex"]; term == nil || term.ID != "http://example.org/vocab#" {
+		t.Errorf("Unmarshal() ex prefix = %v", term)
+	}
+}
+
+const testTriGOut = `@base <http://example.org/> .
+@prefix : <http://example.org/default#> .
+@prefix ex: <http://example.org/vocab#> .
+
+<alice> a ex:Person ;
+	ex:name "Alice", "Alicia"@es ;
+	ex:age 42 ;
+	ex:knows _:b0, _:b1 ;
+	:tags _:b2 .
+
+_:b0 ex:name "Bob" .
+
+_:b2 <http://www.w3.org/1999/02/22-rdf-syntax-ns#first> "a" ;
+	<http://www.w3.org/1999/02/22-rdf-syntax-ns#rest> _:b3 .
+
+_:b3 <http://www.w3.org/1999/02/22-rdf-syntax-ns#first> 1.5 ;
+	<http://www.w3.org/1999/02/22-rdf-syntax-ns#rest> _:b4 .
+
+_:b4 <http://www.w3.org/1999/02/22-rdf-syntax-ns#first> true ;
+	<http://www.w3.org/1999/02/22-rdf-syntax-ns#rest> <http://www.w3.org/1999/02/22-rdf-syntax-ns#nil> .
+
+_:b1 ex:name "Carol\n\"the third\"" .
+
+<g> {
+	<alice> ex:score 1e3 ;
+		ex:date "2020-01-01"^^<http://www.w3.org/2001/XMLSchema#date> .
+}
+`
+
+func TestMarshal(t *testing.T) {
+	_, ctx, err := Unmarshal([]byte(testTriG))
+	if err != nil {
+		t.Fatalf("Unmarshal() = %v", err)
+	}
+
+	b, err := Marshal(testTriGQuads, ctx)
+	if err != nil {
+		t.Fatalf("Marshal() = %v", err)
+	}
+	if string(b) != testTriGOut {
+		t.Errorf("Marshal() = \n%v\nwant \n%v", string(b), testTriGOut)
+	}
+
+	quads, _, err := Unmarshal(b)
+	if err != nil {
+		t.Fatalf("Unmarshal(Marshal()) = %v", err)
+	}
+	if len(quads) != len(testTriGQuads) {
+		t.Errorf("Unmarshal(Marshal()) returned %v quads, want %v", len(quads), len(testTriGQuads))
+	}
+}
+
+func TestUnmarshal_error(t *testing.T) {
+	tests := []struct{
+		in string
+		line, column int
+	}{
+		{"<http://example.org/s> ex:p <http://example.org/o> .", 1, 24},
+		{"@prefix ex: <http://example.org/> .\nex:s ex:p \"o\n\" .", 2, 13},
+		{"<http://example.org/s> <http://example.org/p> <http://example.org/o>", 1, 69},
+	}
+	for _, test := range tests {
+		_, _, err := Unmarshal([]byte(test.in))
+		synErr, ok := err.(*SyntaxError)
+		if !ok {
+			t.Errorf("Unmarshal(%q) = %v, want a syntax error", test.in, err)
+			continue
+		}
+		if synErr.Line != test.line || synErr.Column != test.column {
+			t.Errorf("Unmarshal(%q) = %v, want line %v, column %v", test.in, err, test.line, test.column)
+		}
+	}
+}